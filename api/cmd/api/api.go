@@ -11,10 +11,13 @@ import (
 	"os"
 	"os/signal"
 	"strconv"
+	_ "time/tzdata"
 
 	"github.com/alvinhuhhh/go-alfred/internal/chat"
 	"github.com/alvinhuhhh/go-alfred/internal/config"
+	"github.com/alvinhuhhh/go-alfred/internal/cron"
 	"github.com/alvinhuhhh/go-alfred/internal/dinner"
+	"github.com/alvinhuhhh/go-alfred/internal/grocery"
 	"github.com/alvinhuhhh/go-alfred/internal/handlers"
 	"github.com/alvinhuhhh/go-alfred/internal/middleware"
 	"github.com/alvinhuhhh/go-alfred/internal/secret"
@@ -51,6 +54,15 @@ func main() {
 		log.Fatal(err)
 	}
 
+	chatRepo, err := chat.NewRepo(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	permissions, err := chat.NewPermissions(chatRepo)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	// Bot handler
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer cancel()
@@ -70,7 +82,7 @@ func main() {
 				})
 			}
 		}),
-		bot.WithMiddlewares(middleware.LogBotRequests),
+		bot.WithMiddlewares(middleware.LogBotRequests, chat.TrackMembers(chatRepo), chat.RequirePermission(permissions)),
 	}
 
 	if config.IsTestServer() {
@@ -80,16 +92,24 @@ func main() {
 	if err != nil {
 		log.Fatal(err)
 	}
+	webhookSecret, err := config.GetWebhookSecretToken()
+	if err != nil {
+		if os.Getenv("GO_ENV") == "production" {
+			log.Fatal(err)
+		}
+		slog.Warn(fmt.Sprintf("not verifying webhook updates: %s", err.Error()))
+	} else {
+		opts = append(opts, bot.WithWebhookSecretToken(webhookSecret))
+	}
 	b, err := bot.New(token, opts...)
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	chatRepo, err := chat.NewRepo(db)
-	if err != nil {
+	if err := registerWebhook(ctx, b, webhookSecret); err != nil {
 		log.Fatal(err)
 	}
-	chatService, err := chat.NewService(chatRepo)
+
+	chatService, err := chat.NewService(b, chatRepo)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -103,11 +123,48 @@ func main() {
 		log.Fatal(err)
 	}
 
+	cronRepo, err := cron.NewRepo(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var scheduler cron.Scheduler
+	switch config.GetSchedulerBackend() {
+	case config.SchedulerInProcess:
+		s, err := cron.NewInProcessScheduler(db, cronRepo, dinnerService)
+		if err != nil {
+			log.Fatal(err)
+		}
+		go s.Start(ctx)
+		scheduler = s
+	case config.SchedulerPgCron:
+		s, err := cron.NewPgCronScheduler(cronRepo)
+		if err != nil {
+			log.Fatal(err)
+		}
+		go s.Start(ctx)
+		scheduler = s
+	default:
+		log.Fatal(fmt.Errorf("unknown scheduler backend: %s", config.GetSchedulerBackend()))
+	}
+	cronService, err := cron.NewService(cronRepo, chatRepo, scheduler)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	groceryRepo, err := grocery.NewRepo(db)
+	if err != nil {
+		log.Fatal(err)
+	}
+	groceryService, err := grocery.NewService(b, groceryRepo, chatRepo, chatService)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	secretRepo, err := secret.NewRepo(db)
 	if err != nil {
 		log.Fatal(err)
 	}
-	secretService, err := secret.NewService(secretRepo)
+	secretService, err := secret.NewService(b, secretRepo, chatService, permissions)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -115,11 +172,48 @@ func main() {
 	b.RegisterHandler(bot.HandlerTypeMessageText, "/start", bot.MatchTypePrefix, chatService.Start)
 	b.RegisterHandler(bot.HandlerTypeMessageText, "/app", bot.MatchTypePrefix, chatService.StartApp)
 	b.RegisterHandler(bot.HandlerTypeMessageText, "/hello", bot.MatchTypePrefix, chatService.ReplyHello)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/diet", bot.MatchTypePrefix, chatService.HandleDiet)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/permission", bot.MatchTypePrefix, chatService.HandlePermissions)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/promote", bot.MatchTypePrefix, chatService.HandlePermissions)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/demote", bot.MatchTypePrefix, chatService.HandlePermissions)
 
 	b.RegisterHandler(bot.HandlerTypeMessageText, "/getdinner", bot.MatchTypePrefix, dinnerService.HandleDinner)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/upcomingdinners", bot.MatchTypePrefix, dinnerService.HandleDinner)
 	b.RegisterHandler(bot.HandlerTypeMessageText, "/enddinner", bot.MatchTypePrefix, dinnerService.HandleDinner)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/reopendinner", bot.MatchTypePrefix, dinnerService.HandleDinner)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/late", bot.MatchTypePrefix, dinnerService.HandleDinner)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/dinnerstats", bot.MatchTypePrefix, dinnerService.HandleDinner)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/exportdinners", bot.MatchTypePrefix, dinnerService.HandleDinner)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/menu", bot.MatchTypePrefix, dinnerService.HandleDinner)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/cook", bot.MatchTypePrefix, dinnerService.HandleDinner)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/dinnermode", bot.MatchTypePrefix, dinnerService.HandleDinner)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/unlockdinner", bot.MatchTypePrefix, dinnerService.HandleDinner)
+
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/schedule", bot.MatchTypePrefix, cronService.HandleSchedule)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/reschedule", bot.MatchTypePrefix, cronService.HandleSchedule)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/unschedule", bot.MatchTypePrefix, cronService.HandleSchedule)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/nudge", bot.MatchTypePrefix, cronService.HandleNudge)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/quiethours", bot.MatchTypePrefix, cronService.HandleNudge)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/dinnercutoff", bot.MatchTypePrefix, cronService.HandleSettings)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/timezone", bot.MatchTypePrefix, cronService.HandleSettings)
+
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/buy", bot.MatchTypePrefix, groceryService.HandleGroceries)
+	b.RegisterHandler(bot.HandlerTypeMessageText, "/groceries", bot.MatchTypePrefix, groceryService.HandleGroceries)
+
 	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "joindinner", bot.MatchTypePrefix, dinnerService.HandleCallbackQuery)
 	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "leavedinner", bot.MatchTypePrefix, dinnerService.HandleCallbackQuery)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "maybedinner", bot.MatchTypePrefix, dinnerService.HandleCallbackQuery)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "latedinner", bot.MatchTypePrefix, dinnerService.HandleCallbackQuery)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "takeawaydinner", bot.MatchTypePrefix, dinnerService.HandleCallbackQuery)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "guestsdinner", bot.MatchTypePrefix, dinnerService.HandleCallbackQuery)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "cookdinner", bot.MatchTypePrefix, dinnerService.HandleCallbackQuery)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "repostdinner", bot.MatchTypePrefix, dinnerService.HandleCallbackQuery)
+	b.RegisterHandlerMatchFunc(func(update *models.Update) bool {
+		return update.PollAnswer != nil
+	}, dinnerService.HandlePollAnswer)
+
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "groceryitem", bot.MatchTypePrefix, groceryService.HandleCallbackQuery)
+	b.RegisterHandler(bot.HandlerTypeCallbackQueryData, "groceryclear", bot.MatchTypePrefix, groceryService.HandleCallbackQuery)
 
 	go b.StartWebhook(ctx)
 	slog.Info("Bot webhook listener started")
@@ -138,12 +232,23 @@ func main() {
 	api.Use(middleware.LogRequests)
 	api.Use(middleware.Auth)
 
-	api.HandleFunc("/webhook", b.WebhookHandler()).Methods(http.MethodGet, http.MethodPost, http.MethodPut, http.MethodOptions) // routes to Bot handlers
+	api.Handle("/webhook", b.WebhookHandler()).Methods(http.MethodGet, http.MethodPost, http.MethodPut, http.MethodOptions) // routes to Bot handlers
 	api.HandleFunc("/ping", httpHandler.Ping).Methods(http.MethodGet)
-	api.HandleFunc("/cron", dinnerService.CronTrigger).Methods(http.MethodPost)
+	api.Handle("/cron", middleware.VerifyCronSignature(cronRepo)(http.HandlerFunc(dinnerService.CronTrigger))).Methods(http.MethodPost)
+	api.Handle("/dinners", middleware.RequireChatMember(chatService, middleware.ChatIdFromQuery("chatId"))(http.HandlerFunc(dinnerService.GetDinners))).Methods(http.MethodGet)
+	api.Handle("/dinners/{chatId}/export", middleware.RequireChatMemberOrFeedToken(chatService, chatService, middleware.ChatIdFromPath("chatId"))(http.HandlerFunc(dinnerService.ExportDinners))).Methods(http.MethodGet)
+	api.Handle("/dinners/stats/{chatId}", middleware.RequireChatMember(chatService, middleware.ChatIdFromPath("chatId"))(http.HandlerFunc(dinnerService.GetDinnerStats))).Methods(http.MethodGet)
+
+	api.Handle("/diets/{chatId}", middleware.RequireChatMember(chatService, middleware.ChatIdFromPath("chatId"))(http.HandlerFunc(chatService.GetDiets))).Methods(http.MethodGet)
+	api.Handle("/diets/{chatId}", middleware.RequireChatMember(chatService, middleware.ChatIdFromPath("chatId"))(http.HandlerFunc(chatService.UpdateDiet))).Methods(http.MethodPut)
+
+	api.Handle("/groceries", middleware.RequireChatMember(chatService, middleware.ChatIdFromQuery("chatId"))(http.HandlerFunc(groceryService.GetItems))).Methods(http.MethodGet)
+	api.HandleFunc("/groceries", groceryService.InsertItem).Methods(http.MethodPost)
+	api.HandleFunc("/groceries/{id}", groceryService.UpdateItem).Methods(http.MethodPut)
+	api.HandleFunc("/groceries/{id}", groceryService.DeleteItem).Methods(http.MethodDelete)
 
-	api.HandleFunc("/encryption/key", secretService.GetDataEncryptionKey).Methods(http.MethodGet)
-	api.HandleFunc("/secrets/{chatId}", secretService.GetSecretsForChatId).Methods(http.MethodGet)
+	api.Handle("/encryption/key", middleware.RequireChatMember(chatService, middleware.ChatIdFromQuery("chatId"))(http.HandlerFunc(secretService.GetDataEncryptionKey))).Methods(http.MethodGet)
+	api.Handle("/secrets/{chatId}", middleware.RequireChatMember(chatService, middleware.ChatIdFromPath("chatId"))(http.HandlerFunc(secretService.GetSecretsForChatId))).Methods(http.MethodGet)
 	api.HandleFunc("/secrets", secretService.InsertSecret).Methods(http.MethodPost)
 	api.HandleFunc("/secrets/{id}", secretService.DeleteSecret).Methods(http.MethodDelete)
 
@@ -164,6 +269,31 @@ func getPort() (string, error) {
 	return port, nil
 }
 
+// registerWebhook points Telegram at this app's webhook, signed with the secret token.
+func registerWebhook(ctx context.Context, b *bot.Bot, secret string) error {
+	publicUrl, err := config.GetPublicUrl()
+	if err != nil {
+		slog.Warn(fmt.Sprintf("skipping webhook registration: %s", err.Error()))
+		return nil
+	}
+	_, err = b.SetWebhook(ctx, &bot.SetWebhookParams{
+		URL:         publicUrl + "/api/webhook",
+		SecretToken: secret,
+		AllowedUpdates: []string{
+			models.AllowedUpdateMessage,
+			models.AllowedUpdateCallbackQuery,
+			models.AllowedUpdateChatMember, // only delivered when listed explicitly
+			models.AllowedUpdateMyChatMember,
+			models.AllowedUpdatePollAnswer,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	slog.Info(fmt.Sprintf("registered webhook at %s/api/webhook", publicUrl))
+	return nil
+}
+
 func getDB() (*sqlx.DB, error) {
 	dbUser := os.Getenv("DB_USER")
 	dbPassword := url.QueryEscape(os.Getenv("DB_PASSWORD"))
@@ -0,0 +1,120 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// TrackMembers records the users seen in each update so that chat membership
+// and roles are known without asking Telegram.
+func TrackMembers(r Repo) bot.Middleware {
+	return func(next bot.HandlerFunc) bot.HandlerFunc {
+		return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+			for _, m := range membersFromUpdate(update) {
+				if err := r.UpsertMember(ctx, &m); err != nil {
+					slog.Error(err.Error())
+				}
+			}
+			next(ctx, b, update)
+		}
+	}
+}
+
+func membersFromUpdate(update *models.Update) []Member {
+	members := []Member{}
+	add := func(chatId int64, u *models.User, role Role) {
+		if u == nil || u.IsBot {
+			return
+		}
+		members = append(members, Member{
+			ChatID:    chatId,
+			UserID:    u.ID,
+			FirstName: u.FirstName,
+			LastName:  u.LastName,
+			Username:  u.Username,
+			Role:      role,
+		})
+	}
+
+	if msg := update.Message; msg != nil && msg.Chat.Type != models.ChatTypePrivate {
+		add(msg.Chat.ID, msg.From, "")
+		for i := range msg.NewChatMembers {
+			add(msg.Chat.ID, &msg.NewChatMembers[i], RoleMember)
+		}
+		add(msg.Chat.ID, msg.LeftChatMember, RoleLeft)
+	}
+	if cq := update.CallbackQuery; cq != nil && cq.Message.Message != nil && cq.Message.Message.Chat.Type != models.ChatTypePrivate {
+		add(cq.Message.Message.Chat.ID, &cq.From, "")
+	}
+	if cm := update.ChatMember; cm != nil {
+		user, role := memberRole(cm.NewChatMember)
+		add(cm.Chat.ID, user, role)
+	}
+	return members
+}
+
+// memberRole maps a Telegram chat member status onto a local role.
+func memberRole(m models.ChatMember) (*models.User, Role) {
+	switch m.Type {
+	case models.ChatMemberTypeOwner:
+		return m.Owner.User, RoleAdmin
+	case models.ChatMemberTypeAdministrator:
+		return &m.Administrator.User, RoleAdmin
+	case models.ChatMemberTypeMember:
+		return m.Member.User, RoleMember
+	case models.ChatMemberTypeRestricted:
+		if m.Restricted.IsMember {
+			return m.Restricted.User, RoleMember
+		}
+		return m.Restricted.User, RoleLeft
+	case models.ChatMemberTypeLeft:
+		return m.Left.User, RoleLeft
+	case models.ChatMemberTypeBanned:
+		return m.Banned.User, RoleLeft
+	default:
+		return nil, ""
+	}
+}
+
+// RequirePermission stops commands that the sender is not allowed to run in the chat.
+func RequirePermission(p Permissions) bot.Middleware {
+	return func(next bot.HandlerFunc) bot.HandlerFunc {
+		return func(ctx context.Context, b *bot.Bot, update *models.Update) {
+			msg := update.Message
+			if msg == nil || msg.From == nil {
+				next(ctx, b, update)
+				return
+			}
+			command := CommandName(msg.Text)
+			if command == "" {
+				next(ctx, b, update)
+				return
+			}
+			ok, err := p.CanRun(ctx, b, msg.Chat.ID, msg.From.ID, command)
+			if err != nil {
+				slog.Error(err.Error())
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID: msg.Chat.ID,
+					Text:   "Sorry! Having a bit of trouble, will be back soon!",
+				})
+				return
+			}
+			if !ok {
+				slog.Warn(fmt.Sprintf("user id %d is not allowed to run %s in chat id %d", msg.From.ID, command, msg.Chat.ID))
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID: msg.Chat.ID,
+					Text:   fmt.Sprintf("Sorry, only admins can use /%s here.", command),
+					ReplyParameters: &models.ReplyParameters{
+						MessageID: msg.ID,
+					},
+				})
+				return
+			}
+			next(ctx, b, update)
+		}
+	}
+}
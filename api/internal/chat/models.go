@@ -1,6 +1,115 @@
 package chat
 
+import (
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/alvinhuhhh/go-alfred/internal/config"
+	"github.com/lib/pq"
+)
+
 type Chat struct {
-	ID int64 `db:"id"`
-	Type string `db:"type"`
-}
\ No newline at end of file
+	ID              int64  `db:"id"`
+	Type            string `db:"type"`
+	DinnerCutoff    string `db:"dinner_cutoff"`
+	Timezone        string `db:"timezone"`
+	FeedToken       string `db:"feed_token"`
+	DinnerMode      string `db:"dinner_mode"`
+	NudgeInterval   int    `db:"nudge_interval"`
+	QuietHoursStart string `db:"quiet_hours_start"`
+	QuietHoursEnd   string `db:"quiet_hours_end"`
+}
+
+const (
+	// DinnerModeButtons posts dinners as a message with inline buttons to respond with.
+	DinnerModeButtons = "buttons"
+	// DinnerModePoll posts dinners alongside a native Telegram poll to respond with.
+	DinnerModePoll = "poll"
+)
+
+// UsesPolls reports whether dinners are answered with native Telegram polls in the chat.
+func (c Chat) UsesPolls() bool {
+	return c.DinnerMode == DinnerModePoll
+}
+
+// Location returns the chat's timezone, falling back to the default timezone if it is unset or invalid.
+func (c Chat) Location() *time.Location {
+	name := c.Timezone
+	if name == "" {
+		name = config.GetDefaultTimezone()
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		slog.Error(err.Error())
+		return time.UTC
+	}
+	return loc
+}
+
+// InQuietHours reports whether t falls within the chat's quiet hours, which may run past midnight.
+func (c Chat) InQuietHours(t time.Time) bool {
+	if c.QuietHoursStart == "" || c.QuietHoursEnd == "" {
+		return false
+	}
+	start, err := time.Parse("15:04", c.QuietHoursStart)
+	if err != nil {
+		return false
+	}
+	end, err := time.Parse("15:04", c.QuietHoursEnd)
+	if err != nil {
+		return false
+	}
+	t = t.In(c.Location())
+	now := t.Hour()*60 + t.Minute()
+	from, to := start.Hour()*60+start.Minute(), end.Hour()*60+end.Minute()
+	if from <= to {
+		return now >= from && now < to
+	}
+	return now >= from || now < to
+}
+
+type Role string
+
+const (
+	RoleAdmin  Role = "admin"
+	RoleMember Role = "member"
+	RoleLeft   Role = "left"
+)
+
+type Member struct {
+	ChatID      int64          `db:"chat_id" json:"chatId"`
+	UserID      int64          `db:"user_id" json:"userId"`
+	FirstName   string         `db:"first_name" json:"firstName"`
+	LastName    string         `db:"last_name" json:"lastName"`
+	Username    string         `db:"username" json:"username"`
+	Role        Role           `db:"role" json:"role"`
+	Diet        pq.StringArray `db:"diet" json:"diet"`
+	DMBlockedAt *time.Time     `db:"dm_blocked_at" json:"dmBlockedAt"`
+	UpdatedAt   time.Time      `db:"updated_at" json:"updatedAt"`
+}
+
+// IsMember reports whether the user is currently in the chat.
+func (m Member) IsMember() bool {
+	return m.Role == RoleAdmin || m.Role == RoleMember
+}
+
+// Permission overrides the role needed to run a command in a chat.
+type Permission struct {
+	ChatID  int64  `db:"chat_id" json:"chatId"`
+	Command string `db:"command" json:"command"`
+	Role    Role   `db:"role" json:"role"`
+}
+
+// ParseDiet turns "Vegetarian, no peanuts" into dietary tags like "vegetarian" and "no-peanuts".
+func ParseDiet(text string) []string {
+	tags := []string{}
+	for _, tag := range strings.Split(text, ",") {
+		tag = strings.Join(strings.Fields(strings.ToLower(tag)), "-")
+		if tag != "" && !slices.Contains(tags, tag) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
@@ -0,0 +1,38 @@
+package chat
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func Test_ParseDiet(t *testing.T) {
+	actual := ParseDiet("Vegetarian,  no   peanuts, , vegetarian")
+	expected := []string{"vegetarian", "no-peanuts"}
+	if !slices.Equal(actual, expected) {
+		t.Errorf("expected %v, got %v", expected, actual)
+	}
+}
+
+func Test_InQuietHours(t *testing.T) {
+	c := Chat{Timezone: "UTC", QuietHoursStart: "22:00", QuietHoursEnd: "08:00"}
+	tests := map[string]bool{
+		"21:59": false,
+		"22:00": true,
+		"03:00": true,
+		"07:59": true,
+		"08:00": false,
+		"12:00": false,
+	}
+	for clock, expected := range tests {
+		at, _ := time.Parse("15:04", clock)
+		if actual := c.InQuietHours(at); actual != expected {
+			t.Errorf("expected %s in quiet hours to be %v, got %v", clock, expected, actual)
+		}
+	}
+
+	c.QuietHoursStart, c.QuietHoursEnd = "", ""
+	if c.InQuietHours(time.Now()) {
+		t.Errorf("expected no quiet hours")
+	}
+}
@@ -0,0 +1,127 @@
+package chat
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"strings"
+
+	"github.com/go-telegram/bot"
+)
+
+// CommandDeleteSecret is the policy name for deleting secrets from the Mini App.
+const CommandDeleteSecret = "deletesecret"
+
+// DefaultPolicy lists the commands that chats can restrict, and the role each one needs by default.
+// Commands not listed here can be run by anyone.
+var DefaultPolicy = map[string]Role{
+	"enddinner":         RoleAdmin,
+	"reopendinner":      RoleAdmin,
+	"unlockdinner":      RoleAdmin,
+	"dinnercutoff":      RoleAdmin,
+	"dinnermode":        RoleAdmin,
+	"timezone":          RoleAdmin,
+	"schedule":          RoleAdmin,
+	"reschedule":        RoleAdmin,
+	"unschedule":        RoleAdmin,
+	"nudge":             RoleAdmin,
+	"quiethours":        RoleAdmin,
+	CommandDeleteSecret: RoleAdmin,
+}
+
+// adminCommands always need an admin, so that the policy cannot be changed by members.
+var adminCommands = []string{"permission", "promote", "demote"}
+
+// Permissions decides who may run which commands in a chat.
+type Permissions interface {
+	CanRun(ctx context.Context, b *bot.Bot, chatId, userId int64, command string) (bool, error)
+	IsAdmin(ctx context.Context, b *bot.Bot, chatId, userId int64) (bool, error)
+}
+
+type permissions struct {
+	repo Repo
+}
+
+func NewPermissions(r Repo) (Permissions, error) {
+	return &permissions{repo: r}, nil
+}
+
+// CommandName returns the policy name of a bot command, e.g. "enddinner" for "/enddinner@AlfredBot now".
+func CommandName(text string) string {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || !strings.HasPrefix(fields[0], "/") {
+		return ""
+	}
+	name, _, _ := strings.Cut(strings.TrimPrefix(fields[0], "/"), "@")
+	return strings.ToLower(name)
+}
+
+func (p permissions) CanRun(ctx context.Context, b *bot.Bot, chatId, userId int64, command string) (bool, error) {
+	role, err := p.role(ctx, chatId, command)
+	if err != nil {
+		return false, err
+	}
+	if role != RoleAdmin {
+		return true, nil
+	}
+	return p.IsAdmin(ctx, b, chatId, userId)
+}
+
+// IsAdmin reports whether the user is a Telegram admin of the chat or has been promoted in Alfred.
+func (p permissions) IsAdmin(ctx context.Context, b *bot.Bot, chatId, userId int64) (bool, error) {
+	return hasRole(ctx, p.repo, b, chatId, userId, RoleAdmin)
+}
+
+// hasRole reports whether a user is a member (RoleMember) or an admin (RoleAdmin) of a chat. Admins count
+// as members. The members kept by TrackMembers settle it when they can, which includes admins promoted in
+// Alfred, and Telegram is asked for the user's status otherwise.
+func hasRole(ctx context.Context, r Repo, b *bot.Bot, chatId, userId int64, role Role) (bool, error) {
+	if chatId == userId {
+		// Private chat with the bot
+		return true, nil
+	}
+	satisfies := func(actual Role) bool {
+		return actual == RoleAdmin || (role == RoleMember && actual == RoleMember)
+	}
+
+	m, err := r.GetMember(ctx, chatId, userId)
+	if err != nil && err != sql.ErrNoRows {
+		return false, err
+	}
+	if m != nil && satisfies(m.Role) {
+		return true, nil
+	}
+	member, err := b.GetChatMember(ctx, &bot.GetChatMemberParams{
+		ChatID: chatId,
+		UserID: userId,
+	})
+	if err != nil {
+		if errors.Is(err, bot.ErrorBadRequest) {
+			// Unknown chat or user
+			return false, nil
+		}
+		return false, err
+	}
+	_, actual := memberRole(*member)
+	return satisfies(actual), nil
+}
+
+// role returns the role needed to run command in the chat.
+func (p permissions) role(ctx context.Context, chatId int64, command string) (Role, error) {
+	if slices.Contains(adminCommands, command) {
+		return RoleAdmin, nil
+	}
+	role, ok := DefaultPolicy[command]
+	if !ok {
+		return RoleMember, nil
+	}
+	override, err := p.repo.GetPermission(ctx, chatId, command)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return role, nil
+		}
+		return "", err
+	}
+	return override.Role, nil
+}
@@ -0,0 +1,19 @@
+package chat
+
+import "testing"
+
+func Test_CommandName(t *testing.T) {
+	cases := map[string]string{
+		"/enddinner":               "enddinner",
+		"/EndDinner@AlfredBot now": "enddinner",
+		"/schedules":               "schedules",
+		"  /unschedule 3":          "unschedule",
+		"hello /enddinner":         "",
+		"":                         "",
+	}
+	for text, expected := range cases {
+		if actual := CommandName(text); actual != expected {
+			t.Errorf("CommandName(%q): expected %q, got %q", text, expected, actual)
+		}
+	}
+}
@@ -2,13 +2,26 @@ package chat
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
-type Repo interface{
+type Repo interface {
 	GetChatByID(ctx context.Context, id int64) (*Chat, error)
 	InsertChat(ctx context.Context, chat *Chat) (int64, error)
+	UpdateChat(ctx context.Context, chat *Chat) error
+	SetFeedToken(ctx context.Context, id int64, token string) error
+	GetMembers(ctx context.Context, chatId int64) ([]Member, error)
+	GetMember(ctx context.Context, chatId, userId int64) (*Member, error)
+	GetMemberByUsername(ctx context.Context, chatId int64, username string) (*Member, error)
+	UpsertMember(ctx context.Context, member *Member) error
+	SetDiet(ctx context.Context, chatId, userId int64, diet []string) error
+	MarkDMBlocked(ctx context.Context, chatId, userId int64) (bool, error)
+	ClearDMBlocked(ctx context.Context, userId int64) error
+	GetPermission(ctx context.Context, chatId int64, command string) (*Permission, error)
+	UpsertPermission(ctx context.Context, permission *Permission) error
 }
 
 type repo struct {
@@ -20,7 +33,7 @@ func NewRepo(db *sqlx.DB) (Repo, error) {
 }
 
 func (r repo) GetChatByID(ctx context.Context, id int64) (*Chat, error) {
-	query := "SELECT id, type FROM chats WHERE id = ?"
+	query := "SELECT id, type, dinner_cutoff, timezone, feed_token, dinner_mode, nudge_interval, quiet_hours_start, quiet_hours_end FROM chats WHERE id = ?"
 	query = r.db.Rebind(query)
 	var c Chat
 	if err := r.db.GetContext(ctx, &c, query, id); err != nil {
@@ -37,4 +50,128 @@ func (r repo) InsertChat(ctx context.Context, chat *Chat) (int64, error) {
 		return -1, err
 	}
 	return chat.ID, nil
-}
\ No newline at end of file
+}
+
+func (r repo) UpdateChat(ctx context.Context, chat *Chat) error {
+	query := `UPDATE chats SET dinner_cutoff = ?, timezone = ?, dinner_mode = ?, nudge_interval = ?, quiet_hours_start = ?, quiet_hours_end = ?
+		WHERE id = ?`
+	query = r.db.Rebind(query)
+	_, err := r.db.ExecContext(ctx, query, &chat.DinnerCutoff, &chat.Timezone, &chat.DinnerMode, &chat.NudgeInterval, &chat.QuietHoursStart, &chat.QuietHoursEnd, &chat.ID)
+	return err
+}
+
+// SetFeedToken sets the chat's feed token, unless it already has one.
+func (r repo) SetFeedToken(ctx context.Context, id int64, token string) error {
+	query := "UPDATE chats SET feed_token = ? WHERE id = ? AND feed_token = ''"
+	query = r.db.Rebind(query)
+	_, err := r.db.ExecContext(ctx, query, token, id)
+	return err
+}
+
+func (r repo) GetMembers(ctx context.Context, chatId int64) ([]Member, error) {
+	query := "SELECT chat_id, user_id, first_name, last_name, username, role, diet, dm_blocked_at, updated_at FROM chat_members WHERE chat_id = ? ORDER BY first_name, user_id"
+	query = r.db.Rebind(query)
+	members := []Member{}
+	if err := r.db.SelectContext(ctx, &members, query, chatId); err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (r repo) GetMember(ctx context.Context, chatId, userId int64) (*Member, error) {
+	query := "SELECT chat_id, user_id, first_name, last_name, username, role, diet, dm_blocked_at, updated_at FROM chat_members WHERE chat_id = ? AND user_id = ?"
+	query = r.db.Rebind(query)
+	var m Member
+	if err := r.db.GetContext(ctx, &m, query, chatId, userId); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (r repo) GetMemberByUsername(ctx context.Context, chatId int64, username string) (*Member, error) {
+	query := "SELECT chat_id, user_id, first_name, last_name, username, role, diet, dm_blocked_at, updated_at FROM chat_members WHERE chat_id = ? AND lower(username) = lower(?)"
+	query = r.db.Rebind(query)
+	var m Member
+	if err := r.db.GetContext(ctx, &m, query, chatId, username); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// UpsertMember records a chat member. An empty role means the user was only seen
+// being active in the chat, so an existing admin role is kept and a departed
+// member is marked as back. Members of chats that have not been set up are ignored.
+func (r repo) UpsertMember(ctx context.Context, member *Member) error {
+	query := `INSERT INTO chat_members(chat_id, user_id, first_name, last_name, username, role)
+		SELECT ?, ?, ?, ?, ?, COALESCE(NULLIF(?, ''), 'member')
+		WHERE EXISTS (SELECT 1 FROM chats WHERE id = ?)
+		ON CONFLICT (chat_id, user_id) DO UPDATE SET
+			first_name = EXCLUDED.first_name,
+			last_name = EXCLUDED.last_name,
+			username = EXCLUDED.username,
+			role = CASE
+				WHEN ? <> '' THEN EXCLUDED.role
+				WHEN chat_members.role = 'left' THEN 'member'
+				ELSE chat_members.role
+			END,
+			updated_at = now()`
+	query = r.db.Rebind(query)
+	role := string(member.Role)
+	_, err := r.db.ExecContext(ctx, query, member.ChatID, member.UserID, member.FirstName, member.LastName, member.Username, role, member.ChatID, role)
+	return err
+}
+
+// SetDiet replaces a member's dietary tags. It returns sql.ErrNoRows if the member is not known.
+func (r repo) SetDiet(ctx context.Context, chatId, userId int64, diet []string) error {
+	query := "UPDATE chat_members SET diet = ?, updated_at = now() WHERE chat_id = ? AND user_id = ?"
+	query = r.db.Rebind(query)
+	res, err := r.db.ExecContext(ctx, query, pq.StringArray(diet), chatId, userId)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// MarkDMBlocked records that a member can't be messaged privately, and reports whether this is newly known.
+func (r repo) MarkDMBlocked(ctx context.Context, chatId, userId int64) (bool, error) {
+	query := "UPDATE chat_members SET dm_blocked_at = now() WHERE chat_id = ? AND user_id = ? AND dm_blocked_at IS NULL"
+	query = r.db.Rebind(query)
+	res, err := r.db.ExecContext(ctx, query, chatId, userId)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// ClearDMBlocked records that a user can be messaged privately again, in every chat.
+func (r repo) ClearDMBlocked(ctx context.Context, userId int64) error {
+	query := "UPDATE chat_members SET dm_blocked_at = NULL WHERE user_id = ? AND dm_blocked_at IS NOT NULL"
+	query = r.db.Rebind(query)
+	_, err := r.db.ExecContext(ctx, query, userId)
+	return err
+}
+
+func (r repo) GetPermission(ctx context.Context, chatId int64, command string) (*Permission, error) {
+	query := "SELECT chat_id, command, role FROM chat_permissions WHERE chat_id = ? AND command = ?"
+	query = r.db.Rebind(query)
+	var p Permission
+	if err := r.db.GetContext(ctx, &p, query, chatId, command); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r repo) UpsertPermission(ctx context.Context, permission *Permission) error {
+	query := "INSERT INTO chat_permissions(chat_id, command, role) VALUES (?,?,?) ON CONFLICT (chat_id, command) DO UPDATE SET role = EXCLUDED.role"
+	query = r.db.Rebind(query)
+	_, err := r.db.ExecContext(ctx, query, permission.ChatID, permission.Command, permission.Role)
+	return err
+}
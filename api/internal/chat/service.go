@@ -2,30 +2,54 @@ package chat
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/subtle"
 	"database/sql"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
 
+	"github.com/alvinhuhhh/go-alfred/internal/middleware"
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
+	"github.com/gorilla/mux"
 )
 
 type Service interface {
 	Start(ctx context.Context, b *bot.Bot, update *models.Update)
 	StartApp(ctx context.Context, b *bot.Bot, update *models.Update)
 	ReplyHello(ctx context.Context, b *bot.Bot, update *models.Update)
+	IsChatMember(ctx context.Context, chatId, userId int64) (bool, error)
+	HandlePermissions(ctx context.Context, b *bot.Bot, update *models.Update)
+	VerifyFeedToken(ctx context.Context, chatId int64, token string) (bool, error)
+	HandleDiet(ctx context.Context, b *bot.Bot, update *models.Update)
+	GetDiets(w http.ResponseWriter, r *http.Request)
+	UpdateDiet(w http.ResponseWriter, r *http.Request)
 }
 
 type service struct {
+	bot  *bot.Bot
 	repo Repo
 }
 
-func NewService(r Repo) (Service, error) {
-	return &service{repo: r}, nil
+func NewService(b *bot.Bot, r Repo) (Service, error) {
+	return &service{bot: b, repo: r}, nil
 }
 
 func (s *service) Start(ctx context.Context, b *bot.Bot, update *models.Update) {
+	// Starting a private chat lets the user get reminders by DM
+	if update.Message.Chat.Type == models.ChatTypePrivate && update.Message.From != nil {
+		if err := s.repo.ClearDMBlocked(ctx, update.Message.From.ID); err != nil {
+			slog.Error(err.Error())
+		}
+	}
+
 	_, err := s.repo.GetChatByID(ctx, update.Message.Chat.ID)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -100,3 +124,328 @@ func (s *service) ReplyHello(ctx context.Context, b *bot.Bot, update *models.Upd
 		Text:   "Hello there! What can I do for you today?",
 	})
 }
+
+func (s *service) IsChatMember(ctx context.Context, chatId, userId int64) (bool, error) {
+	return hasRole(ctx, s.repo, s.bot, chatId, userId, RoleMember)
+}
+
+func (s *service) VerifyFeedToken(ctx context.Context, chatId int64, token string) (bool, error) {
+	c, err := s.repo.GetChatByID(ctx, chatId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	if c.FeedToken == "" {
+		return false, nil
+	}
+	return subtle.ConstantTimeCompare([]byte(c.FeedToken), []byte(token)) == 1, nil
+}
+
+// FeedToken returns the token that calendar apps use to poll the chat's feeds, generating it the first time.
+func FeedToken(ctx context.Context, r Repo, chatId int64) (string, error) {
+	c, err := r.GetChatByID(ctx, chatId)
+	if err != nil {
+		return "", err
+	}
+	if c.FeedToken != "" {
+		return c.FeedToken, nil
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	if err := r.SetFeedToken(ctx, chatId, hex.EncodeToString(buf)); err != nil {
+		return "", err
+	}
+	// Read it back in case another request set it first
+	c, err = r.GetChatByID(ctx, chatId)
+	if err != nil {
+		return "", err
+	}
+	return c.FeedToken, nil
+}
+
+func (s *service) HandlePermissions(ctx context.Context, b *bot.Bot, update *models.Update) {
+	chatId := update.Message.Chat.ID
+	args := strings.Fields(update.Message.Text)
+
+	if _, err := s.repo.GetChatByID(ctx, chatId); err != nil {
+		if err == sql.ErrNoRows {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "Please /start me first!",
+			})
+			return
+		}
+		slog.Error(err.Error())
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatId,
+			Text:   "Sorry! Having a bit of trouble, will be back soon!",
+		})
+		return
+	}
+
+	switch CommandName(update.Message.Text) {
+	case "permission":
+		if len(args) == 1 {
+			text, err := s.listPermissions(ctx, chatId)
+			if err != nil {
+				slog.Error(err.Error())
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID: chatId,
+					Text:   "Sorry! Having a bit of trouble, will be back soon!",
+				})
+				return
+			}
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   text,
+			})
+			return
+		}
+		command := strings.ToLower(strings.TrimPrefix(args[1], "/"))
+		_, ok := DefaultPolicy[command]
+		if len(args) != 3 || !ok || !slices.Contains([]string{"admin", "everyone"}, args[2]) {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "Usage: /permission <command> <admin|everyone>, e.g. /permission enddinner everyone",
+			})
+			return
+		}
+		p := Permission{ChatID: chatId, Command: command, Role: RoleAdmin}
+		if args[2] == "everyone" {
+			p.Role = RoleMember
+		}
+		if err := s.repo.UpsertPermission(ctx, &p); err != nil {
+			slog.Error(err.Error())
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "Sorry! Having a bit of trouble, will be back soon!",
+			})
+			return
+		}
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatId,
+			Text:   fmt.Sprintf("Done! %s can now be used by %s.", command, policyLabel(p.Role)),
+		})
+	case "promote", "demote":
+		reply := update.Message.ReplyToMessage
+		if reply == nil || reply.From == nil || reply.From.IsBot {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   fmt.Sprintf("Reply to someone's message with /%s to change their role.", CommandName(update.Message.Text)),
+			})
+			return
+		}
+		m := Member{
+			ChatID:    chatId,
+			UserID:    reply.From.ID,
+			FirstName: reply.From.FirstName,
+			LastName:  reply.From.LastName,
+			Username:  reply.From.Username,
+			Role:      RoleAdmin,
+		}
+		text := fmt.Sprintf("%s is now an admin for Alfred.", m.FirstName)
+		if CommandName(update.Message.Text) == "demote" {
+			m.Role = RoleMember
+			text = fmt.Sprintf("%s is no longer an admin for Alfred.", m.FirstName)
+		}
+		if err := s.repo.UpsertMember(ctx, &m); err != nil {
+			slog.Error(err.Error())
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "Sorry! Having a bit of trouble, will be back soon!",
+			})
+			return
+		}
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatId,
+			Text:   text,
+		})
+	}
+}
+
+func (s *service) HandleDiet(ctx context.Context, b *bot.Bot, update *models.Update) {
+	chatId := update.Message.Chat.ID
+	user := update.Message.From
+
+	if _, err := s.repo.GetChatByID(ctx, chatId); err != nil {
+		if err == sql.ErrNoRows {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "Please /start me first!",
+			})
+			return
+		}
+		slog.Error(err.Error())
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatId,
+			Text:   "Sorry! Having a bit of trouble, will be back soon!",
+		})
+		return
+	}
+
+	args := strings.Fields(update.Message.Text)
+	if len(args) == 1 {
+		text := fmt.Sprintf("%s, you have no dietary requirements. Set them with e.g. /diet vegetarian, no peanuts", user.FirstName)
+		m, err := s.repo.GetMember(ctx, chatId, user.ID)
+		if err != nil && err != sql.ErrNoRows {
+			slog.Error(err.Error())
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "Sorry! Having a bit of trouble, will be back soon!",
+			})
+			return
+		}
+		if m != nil && len(m.Diet) > 0 {
+			text = fmt.Sprintf("%s, your dietary requirements are: %s. Clear them with /diet none", user.FirstName, strings.Join(m.Diet, ", "))
+		}
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatId,
+			Text:   text,
+		})
+		return
+	}
+
+	diet := ParseDiet(strings.Join(args[1:], " "))
+	if len(diet) == 1 && diet[0] == "none" {
+		diet = []string{}
+	}
+	err := s.repo.SetDiet(ctx, chatId, user.ID, diet)
+	if err == sql.ErrNoRows {
+		// Members are only tracked in groups, so add the user here first
+		err = s.repo.UpsertMember(ctx, &Member{ChatID: chatId, UserID: user.ID, FirstName: user.FirstName, LastName: user.LastName, Username: user.Username})
+		if err == nil {
+			err = s.repo.SetDiet(ctx, chatId, user.ID, diet)
+		}
+	}
+	if err != nil {
+		slog.Error(err.Error())
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatId,
+			Text:   "Sorry! Having a bit of trouble, will be back soon!",
+		})
+		return
+	}
+	text := fmt.Sprintf("Got it %s, no dietary requirements.", user.FirstName)
+	if len(diet) > 0 {
+		text = fmt.Sprintf("Got it %s: %s.", user.FirstName, strings.Join(diet, ", "))
+	}
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatId,
+		Text:   text,
+	})
+}
+
+func (s *service) GetDiets(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	chatId, err := strconv.ParseInt(vars["chatId"], 10, 64)
+	if err != nil {
+		slog.Error(err.Error())
+		slog.Error("unable to parse chatId from request")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	members, err := s.repo.GetMembers(r.Context(), chatId)
+	if err != nil {
+		slog.Error(err.Error())
+		slog.Error("error fetching chat members")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(members)
+}
+
+// UpdateDiet sets the caller's dietary tags in a chat.
+func (s *service) UpdateDiet(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	chatId, err := strconv.ParseInt(vars["chatId"], 10, 64)
+	if err != nil {
+		slog.Error(err.Error())
+		slog.Error("unable to parse chatId from request")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	var req struct {
+		UserID int64    `json:"userId"`
+		Diet   []string `json:"diet"`
+	}
+	if err := decoder.Decode(&req); err != nil {
+		slog.Error(err.Error())
+		slog.Error("error parsing request body")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if _, err := s.repo.GetChatByID(r.Context(), chatId); err != nil {
+		if err == sql.ErrNoRows {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		slog.Error(err.Error())
+		slog.Error("error fetching chat")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if data, ok := middleware.InitDataFromContext(r.Context()); ok {
+		req.UserID = data.User.ID
+		// The caller may not have been seen in the chat yet, and init data says who they are
+		member := Member{ChatID: chatId, UserID: data.User.ID, FirstName: data.User.FirstName, LastName: data.User.LastName, Username: data.User.Username}
+		if err := s.repo.UpsertMember(r.Context(), &member); err != nil {
+			slog.Error(err.Error())
+			slog.Error("unable to update chat member")
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+	}
+	if req.UserID == 0 {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	diet := ParseDiet(strings.Join(req.Diet, ","))
+	if err := s.repo.SetDiet(r.Context(), chatId, req.UserID, diet); err != nil {
+		if err == sql.ErrNoRows {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		slog.Error(err.Error())
+		slog.Error("unable to update diet")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(diet)
+}
+
+// listPermissions describes who can run each restricted command in the chat.
+func (s *service) listPermissions(ctx context.Context, chatId int64) (string, error) {
+	commands := make([]string, 0, len(DefaultPolicy))
+	for c := range DefaultPolicy {
+		commands = append(commands, c)
+	}
+	slices.Sort(commands)
+
+	lines := []string{"Permissions:"}
+	for _, c := range commands {
+		role := DefaultPolicy[c]
+		p, err := s.repo.GetPermission(ctx, chatId, c)
+		if err != nil && err != sql.ErrNoRows {
+			return "", err
+		}
+		if p != nil {
+			role = p.Role
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", c, policyLabel(role)))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func policyLabel(role Role) string {
+	if role == RoleAdmin {
+		return "admins only"
+	}
+	return "everyone"
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"errors"
 	"os"
+	"strings"
 )
 
 func IsTestServer() bool {
@@ -20,3 +21,53 @@ func GetBotToken() (string, error) {
 	}
 	return t, nil
 }
+
+// GetDefaultTimezone returns the IANA timezone used for chats that have not set their own.
+func GetDefaultTimezone() string {
+	t, found := os.LookupEnv("DEFAULT_TIMEZONE")
+	if !found || t == "" {
+		return "UTC"
+	}
+	return t
+}
+
+// GetPublicUrl returns the externally reachable base URL of the app, e.g. https://alfred.example.com
+func GetPublicUrl() (string, error) {
+	u, found := os.LookupEnv("PUBLIC_URL")
+	if !found || u == "" {
+		return "", errors.New("PUBLIC_URL is not found")
+	}
+	return strings.TrimSuffix(u, "/"), nil
+}
+
+const (
+	SchedulerPgCron    = "pgcron"
+	SchedulerInProcess = "inprocess"
+)
+
+// GetSchedulerBackend returns how schedules are run, either with pg_cron (default) or in-process.
+func GetSchedulerBackend() string {
+	t, found := os.LookupEnv("SCHEDULER_BACKEND")
+	if !found || t == "" {
+		return SchedulerPgCron
+	}
+	return t
+}
+
+// GetCronSecret returns the shared secret used to sign requests to /api/cron.
+func GetCronSecret() (string, error) {
+	t, found := os.LookupEnv("CRON_SECRET")
+	if !found || t == "" {
+		return "", errors.New("CRON_SECRET is not found")
+	}
+	return t, nil
+}
+
+// GetWebhookSecretToken returns the secret Telegram sends in the X-Telegram-Bot-Api-Secret-Token header.
+func GetWebhookSecretToken() (string, error) {
+	t, found := os.LookupEnv("WEBHOOK_SECRET_TOKEN")
+	if !found || t == "" {
+		return "", errors.New("WEBHOOK_SECRET_TOKEN is not found")
+	}
+	return t, nil
+}
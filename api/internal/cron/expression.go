@@ -0,0 +1,193 @@
+package cron
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseDays parses a day specification such as "mon-fri", "sat,sun", "daily", "weekdays" or
+// "weekends" into a sorted list of weekdays.
+func ParseDays(spec string) ([]time.Weekday, error) {
+	spec = strings.ToLower(strings.TrimSpace(spec))
+	switch spec {
+	case "daily", "everyday", "*":
+		spec = "sun-sat"
+	case "weekdays":
+		spec = "mon-fri"
+	case "weekends":
+		spec = "sat,sun"
+	}
+
+	days := []time.Weekday{}
+	for _, part := range strings.Split(spec, ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		start, err := parseWeekday(from)
+		if err != nil {
+			return nil, err
+		}
+		end := start
+		if isRange {
+			end, err = parseWeekday(to)
+			if err != nil {
+				return nil, err
+			}
+		}
+		for d := start; ; d = (d + 1) % 7 {
+			if !slices.Contains(days, d) {
+				days = append(days, d)
+			}
+			if d == end {
+				break
+			}
+		}
+	}
+	slices.Sort(days)
+	return days, nil
+}
+
+func parseWeekday(s string) (time.Weekday, error) {
+	s = strings.TrimSpace(s)
+	for i := range 7 {
+		name := strings.ToLower(time.Weekday(i).String())
+		if s == name || s == name[:3] {
+			return time.Weekday(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown day: %s", s)
+}
+
+// ToCronExpression converts days and a wall clock time (HH:MM) in loc into a cron expression in UTC,
+// which is the timezone pg_cron runs in. The UTC offset is the one in effect at the given time, so the
+// expression has to be worked out again when loc changes to or from daylight saving.
+func ToCronExpression(days []time.Weekday, clock string, loc *time.Location, at time.Time) (string, error) {
+	if len(days) == 0 {
+		return "", fmt.Errorf("no days given")
+	}
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return "", err
+	}
+	_, offset := at.In(loc).Zone()
+
+	minutes := t.Hour()*60 + t.Minute() - offset/60
+	dayShift := 0
+	for minutes < 0 {
+		minutes += 24 * 60
+		dayShift--
+	}
+	for minutes >= 24*60 {
+		minutes -= 24 * 60
+		dayShift++
+	}
+
+	dow := "*"
+	if len(days) < 7 {
+		shifted := []int{}
+		for _, d := range days {
+			shifted = append(shifted, ((int(d)+dayShift)%7+7)%7)
+		}
+		slices.Sort(shifted)
+		parts := []string{}
+		for _, d := range shifted {
+			parts = append(parts, strconv.Itoa(d))
+		}
+		dow = strings.Join(parts, ",")
+	}
+	return fmt.Sprintf("%d %d * * %s", minutes%60, minutes/60, dow), nil
+}
+
+// Expression is a parsed five field cron expression (minute, hour, day of month, month, day of week).
+type Expression struct {
+	minute, hour, dom, month, dow []bool
+	domAny, dowAny                bool
+}
+
+// ParseExpression parses a standard cron expression. Each field supports "*", single values,
+// ranges ("1-5"), lists ("1,3,5") and steps ("*/15").
+func ParseExpression(expr string) (*Expression, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields in cron expression: %s", expr)
+	}
+
+	var e Expression
+	var err error
+	if e.minute, err = parseField(fields[0], 0, 59); err != nil {
+		return nil, err
+	}
+	if e.hour, err = parseField(fields[1], 0, 23); err != nil {
+		return nil, err
+	}
+	if e.dom, err = parseField(fields[2], 1, 31); err != nil {
+		return nil, err
+	}
+	if e.month, err = parseField(fields[3], 1, 12); err != nil {
+		return nil, err
+	}
+	if e.dow, err = parseField(fields[4], 0, 7); err != nil {
+		return nil, err
+	}
+	e.domAny = fields[2] == "*"
+	e.dowAny = fields[4] == "*"
+	return &e, nil
+}
+
+func parseField(field string, min, max int) ([]bool, error) {
+	set := make([]bool, max+1)
+	for _, part := range strings.Split(field, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			s, err := strconv.Atoi(stepStr)
+			if err != nil || s < 1 {
+				return nil, fmt.Errorf("invalid step in cron field: %s", field)
+			}
+			step = s
+		}
+
+		lo, hi := min, max
+		if rng != "*" {
+			from, to, isRange := strings.Cut(rng, "-")
+			var err error
+			if lo, err = strconv.Atoi(from); err != nil {
+				return nil, fmt.Errorf("invalid value in cron field: %s", field)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(to); err != nil {
+					return nil, fmt.Errorf("invalid value in cron field: %s", field)
+				}
+			} else if hasStep {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("value out of range in cron field: %s", field)
+		}
+		for i := lo; i <= hi; i += step {
+			set[i] = true
+		}
+	}
+	return set, nil
+}
+
+// Matches reports whether the expression fires at the minute of t, evaluated in UTC.
+func (e Expression) Matches(t time.Time) bool {
+	t = t.UTC()
+	if !e.minute[t.Minute()] || !e.hour[t.Hour()] || !e.month[int(t.Month())] {
+		return false
+	}
+
+	wd := int(t.Weekday())
+	dom := e.dom[t.Day()]
+	dow := e.dow[wd] || (wd == 0 && e.dow[7])
+	if e.domAny || e.dowAny {
+		// Only one of the day fields is restricted, so both have to match
+		return dom && dow
+	}
+	// Cron fires when either restricted day field matches
+	return dom || dow
+}
@@ -0,0 +1,186 @@
+package cron
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func Test_ParseDays(t *testing.T) {
+	cases := map[string][]time.Weekday{
+		"mon-fri":   {time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+		"sat,sun":   {time.Sunday, time.Saturday},
+		"fri-mon":   {time.Sunday, time.Monday, time.Friday, time.Saturday},
+		"weekends":  {time.Sunday, time.Saturday},
+		"Wednesday": {time.Wednesday},
+		"daily":     {time.Sunday, time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday},
+	}
+	for input, expected := range cases {
+		actual, err := ParseDays(input)
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", input, err)
+			continue
+		}
+		if !slices.Equal(expected, actual) {
+			t.Errorf("expected %v for %q, got %v", expected, input, actual)
+		}
+	}
+}
+
+func Test_ParseDaysInvalid(t *testing.T) {
+	if _, err := ParseDays("someday"); err == nil {
+		t.Errorf("expected error for invalid days")
+	}
+}
+
+func Test_ToCronExpression(t *testing.T) {
+	days := []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+
+	actual, err := ToCronExpression(days, "16:00", time.UTC, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual != "0 16 * * 1,2,3,4,5" {
+		t.Errorf("unexpected cron expression: %s", actual)
+	}
+}
+
+func Test_ToCronExpressionPreviousDayInUTC(t *testing.T) {
+	sg := time.FixedZone("SGT", 8*60*60)
+	days := []time.Weekday{time.Sunday, time.Monday}
+
+	// 07:30 in Singapore is 23:30 the previous day in UTC
+	actual, err := ToCronExpression(days, "07:30", sg, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual != "30 23 * * 0,6" {
+		t.Errorf("unexpected cron expression: %s", actual)
+	}
+}
+
+func Test_ToCronExpressionEveryDay(t *testing.T) {
+	sg := time.FixedZone("SGT", 8*60*60)
+	days, _ := ParseDays("daily")
+
+	actual, err := ToCronExpression(days, "16:00", sg, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual != "0 8 * * *" {
+		t.Errorf("unexpected cron expression: %s", actual)
+	}
+}
+
+func Test_ToCronExpressionDaylightSaving(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	days, _ := ParseDays("daily")
+
+	// 16:00 in New York is 21:00 UTC in winter (EST) and 20:00 UTC in summer (EDT)
+	cases := map[time.Time]string{
+		time.Date(2026, 1, 15, 12, 0, 0, 0, ny): "0 21 * * *",
+		time.Date(2026, 7, 15, 12, 0, 0, 0, ny): "0 20 * * *",
+	}
+	for at, expected := range cases {
+		actual, err := ToCronExpression(days, "16:00", ny, at)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual != expected {
+			t.Errorf("expected %q on %s, got %q", expected, at.Format("2006-01-02"), actual)
+		}
+	}
+}
+
+func Test_ScheduleDueAcrossDaylightSaving(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Saved in winter, when 16:00 in New York was 21:00 UTC
+	sc := Schedule{Days: "daily", Time: "16:00", CronExpression: "0 21 * * *", Timezone: "America/New_York"}
+
+	for _, at := range []time.Time{
+		time.Date(2026, 1, 15, 16, 0, 0, 0, ny),
+		time.Date(2026, 7, 15, 16, 0, 0, 0, ny),
+	} {
+		if due, err := sc.Due(at); err != nil || !due {
+			t.Errorf("expected schedule to be due at %s, got %v (%v)", at, due, err)
+		}
+		if due, _ := sc.Due(at.Add(time.Hour)); due {
+			t.Errorf("expected schedule not to be due at %s", at.Add(time.Hour))
+		}
+	}
+
+	expr, ok, err := sc.expressionAt(time.Date(2026, 7, 15, 12, 0, 0, 0, ny))
+	if err != nil || !ok || expr != "0 20 * * *" {
+		t.Errorf("expected summer expression %q, got %q (%v, %v)", "0 20 * * *", expr, ok, err)
+	}
+}
+
+func Test_ScheduleDueAtInterval(t *testing.T) {
+	sc := Schedule{Days: "daily", Time: "every 5 minutes", CronExpression: "*/5 * * * *"}
+	if due, err := sc.Due(time.Date(2026, 10, 18, 10, 5, 0, 0, time.UTC)); err != nil || !due {
+		t.Errorf("expected interval schedule to be due, got %v (%v)", due, err)
+	}
+	if _, ok, _ := sc.expressionAt(time.Now()); ok {
+		t.Errorf("expected interval schedule to keep its expression")
+	}
+}
+
+func Test_ExpressionMatches(t *testing.T) {
+	e, err := ParseExpression("30 8 * * 1-5")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	monday := time.Date(2026, 10, 19, 8, 30, 0, 0, time.UTC)
+	if !e.Matches(monday) {
+		t.Errorf("expected expression to match %s", monday)
+	}
+	if e.Matches(monday.Add(time.Minute)) {
+		t.Errorf("expected expression not to match %s", monday.Add(time.Minute))
+	}
+	sunday := time.Date(2026, 10, 18, 8, 30, 0, 0, time.UTC)
+	if e.Matches(sunday) {
+		t.Errorf("expected expression not to match %s", sunday)
+	}
+}
+
+func Test_ExpressionMatchesInUTC(t *testing.T) {
+	e, err := ParseExpression("0 8 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sg := time.FixedZone("SGT", 8*60*60)
+	if !e.Matches(time.Date(2026, 10, 19, 16, 0, 0, 0, sg)) {
+		t.Errorf("expected expression to match 16:00 in Singapore")
+	}
+}
+
+func Test_ExpressionSteps(t *testing.T) {
+	e, err := ParseExpression("*/15 * * * 0,7")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sunday := time.Date(2026, 10, 18, 10, 45, 0, 0, time.UTC)
+	if !e.Matches(sunday) {
+		t.Errorf("expected expression to match %s", sunday)
+	}
+	if e.Matches(sunday.Add(5 * time.Minute)) {
+		t.Errorf("expected expression not to match %s", sunday.Add(5*time.Minute))
+	}
+}
+
+func Test_ParseExpressionInvalid(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "5-1 * * * *"} {
+		if _, err := ParseExpression(expr); err == nil {
+			t.Errorf("expected error for %q", expr)
+		}
+	}
+}
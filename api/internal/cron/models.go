@@ -0,0 +1,68 @@
+package cron
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/alvinhuhhh/go-alfred/internal/chat"
+)
+
+type Schedule struct {
+	ID             int64     `db:"id" json:"id"`
+	ChatID         int64     `db:"chat_id" json:"chatId"`
+	Job            string    `db:"job" json:"job"`
+	Days           string    `db:"days" json:"days"`
+	Time           string    `db:"time" json:"time"`
+	CronExpression string    `db:"cron_expression" json:"cronExpression"`
+	CreatedAt      time.Time `db:"created_at" json:"createdAt"`
+
+	// Timezone of the chat, only loaded by GetAllSchedules
+	Timezone string `db:"timezone" json:"-"`
+}
+
+// JobName is the name of the pg_cron job backing this schedule.
+func (s Schedule) JobName() string {
+	return fmt.Sprintf("alfred_schedule_%d", s.ID)
+}
+
+// Location returns the timezone the schedule's time of day is in, which is its chat's.
+func (s Schedule) Location() *time.Location {
+	return chat.Chat{Timezone: s.Timezone}.Location()
+}
+
+// Due reports whether the schedule fires at the minute of t. Schedules set at a time of day are
+// evaluated in their chat's timezone, so they follow its daylight saving changes, while the rest
+// (such as nudge checks every few minutes) use their cron expression.
+func (s Schedule) Due(t time.Time) (bool, error) {
+	if _, err := time.Parse("15:04", s.Time); err != nil {
+		e, err := ParseExpression(s.CronExpression)
+		if err != nil {
+			return false, err
+		}
+		return e.Matches(t), nil
+	}
+	days, err := ParseDays(s.Days)
+	if err != nil {
+		return false, err
+	}
+	local := t.In(s.Location())
+	return slices.Contains(days, local.Weekday()) && local.Format("15:04") == s.Time, nil
+}
+
+// expressionAt works out the cron expression of the schedule with its chat's UTC offset at t. It
+// returns false for schedules that are not set at a time of day, whose expression never changes.
+func (s Schedule) expressionAt(t time.Time) (string, bool, error) {
+	if _, err := time.Parse("15:04", s.Time); err != nil {
+		return "", false, nil
+	}
+	days, err := ParseDays(s.Days)
+	if err != nil {
+		return "", false, err
+	}
+	expr, err := ToCronExpression(days, s.Time, s.Location(), t)
+	if err != nil {
+		return "", false, err
+	}
+	return expr, true, nil
+}
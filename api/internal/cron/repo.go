@@ -3,13 +3,22 @@ package cron
 import (
 	"context"
 	"encoding/json"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 )
 
 type Repo interface {
-	Schedule(ctx context.Context, jobname, schedule, url string, chat_id int64) error
+	Schedule(ctx context.Context, jobname, schedule, url string, chat_id int64, job string) error
+	SaveCronSecret(ctx context.Context, secret string) error
 	Unschedule(ctx context.Context, jobname string) error
+	UseNonce(ctx context.Context, nonce string, expiry time.Duration) (bool, error)
+	GetAllSchedules(ctx context.Context) ([]Schedule, error)
+	GetSchedulesForChatId(ctx context.Context, chatId int64) ([]Schedule, error)
+	GetScheduleById(ctx context.Context, id int64) (*Schedule, error)
+	InsertSchedule(ctx context.Context, s *Schedule) (int64, error)
+	UpdateSchedule(ctx context.Context, s *Schedule) error
+	DeleteSchedule(ctx context.Context, id int64) error
 }
 
 type repo struct {
@@ -20,35 +29,67 @@ func NewRepo(db *sqlx.DB) (Repo, error) {
 	return &repo{db: db}, nil
 }
 
-func (r repo) Schedule(ctx context.Context, jobname, schedule, url string, chat_id int64) error {
+func (r repo) Schedule(ctx context.Context, jobname, schedule, url string, chat_id int64, job string) error {
+	// The command runs later inside pg_cron, so values are quoted into it rather than bound.
+	// Each run signs the body with a fresh timestamp and nonce, the same way as util.SignRequest.
+	// The secret is read from cron_settings when the job runs, so that it isn't kept in cron.job and
+	// follows rotation. hmac() comes from the pgcrypto extension, see migration 21, and is qualified
+	// with its schema as that is not always on pg_cron's search_path (e.g. "extensions" on Supabase).
 	query := `
 		SELECT cron.schedule(
 			?,
 			?,
-			$$
-				SELECT
-					net.http_post(
-						url:=?,
-						body:=?
-					) AS request_id
-			$$
+			format(
+				$cmd$
+					SELECT
+						net.http_post(
+							url:=%1$L,
+							body:=%2$L::jsonb,
+							headers:=jsonb_build_object(
+								'Content-Type', 'application/json',
+								'X-Alfred-Timestamp', s.ts,
+								'X-Alfred-Nonce', s.nonce,
+								'X-Alfred-Signature', encode(%3$s.hmac(s.ts || '.' || s.nonce || '.' || (%2$L::jsonb)::text, s.secret, 'sha256'), 'hex')
+							)
+						) AS request_id
+					FROM (
+						SELECT
+							extract(epoch FROM now())::bigint::text AS ts,
+							gen_random_uuid()::text AS nonce,
+							(SELECT value FROM public.cron_settings WHERE name = 'cron_secret') AS secret
+					) AS s
+				$cmd$,
+				?::text,
+				?::text,
+				(SELECT extnamespace::regnamespace::text FROM pg_extension WHERE extname = 'pgcrypto')
+			)
 		)
 	`
 	query = r.db.Rebind(query)
 
 	bodyData := map[string]interface{}{
 		"chat_id": chat_id,
+		"job":     job,
 	}
 	body, err := json.Marshal(bodyData)
 	if err != nil {
 		return err
 	}
-	if _, err := r.db.ExecContext(ctx, query, jobname, schedule, url, body); err != nil {
+	if _, err := r.db.ExecContext(ctx, query, jobname, schedule, url, string(body)); err != nil {
 		return err
 	}
 	return nil
 }
 
+// SaveCronSecret stores the secret that pg_cron jobs sign their requests with.
+func (r repo) SaveCronSecret(ctx context.Context, secret string) error {
+	query := `INSERT INTO cron_settings(name, value) VALUES ('cron_secret', ?)
+		ON CONFLICT (name) DO UPDATE SET value = EXCLUDED.value, updated_at = now()`
+	query = r.db.Rebind(query)
+	_, err := r.db.ExecContext(ctx, query, secret)
+	return err
+}
+
 func (r repo) Unschedule(ctx context.Context, jobname string) error {
 	query := "SELECT cron.unschedule(?)"
 	query = r.db.Rebind(query)
@@ -58,3 +99,73 @@ func (r repo) Unschedule(ctx context.Context, jobname string) error {
 	}
 	return nil
 }
+
+func (r repo) GetAllSchedules(ctx context.Context) ([]Schedule, error) {
+	query := "SELECT s.id, s.chat_id, s.job, s.days, s.time, s.cron_expression, s.created_at, c.timezone FROM schedules s JOIN chats c ON c.id = s.chat_id ORDER BY s.id"
+	s := []Schedule{}
+	err := r.db.SelectContext(ctx, &s, query)
+	return s, err
+}
+
+func (r repo) GetSchedulesForChatId(ctx context.Context, chatId int64) ([]Schedule, error) {
+	query := "SELECT id, chat_id, job, days, time, cron_expression, created_at FROM schedules WHERE chat_id = ? ORDER BY id"
+	query = r.db.Rebind(query)
+	s := []Schedule{}
+	err := r.db.SelectContext(ctx, &s, query, chatId)
+	return s, err
+}
+
+func (r repo) GetScheduleById(ctx context.Context, id int64) (*Schedule, error) {
+	query := "SELECT id, chat_id, job, days, time, cron_expression, created_at FROM schedules WHERE id = ?"
+	query = r.db.Rebind(query)
+	var s Schedule
+	if err := r.db.GetContext(ctx, &s, query, id); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r repo) InsertSchedule(ctx context.Context, s *Schedule) (int64, error) {
+	query := "INSERT INTO schedules(chat_id, job, days, time, cron_expression) VALUES (?,?,?,?,?) RETURNING id"
+	query = r.db.Rebind(query)
+	var id int64
+	err := r.db.QueryRowContext(ctx, query, &s.ChatID, &s.Job, &s.Days, &s.Time, &s.CronExpression).Scan(&id)
+	if err != nil {
+		return -1, err
+	}
+	return id, nil
+}
+
+func (r repo) UpdateSchedule(ctx context.Context, s *Schedule) error {
+	query := "UPDATE schedules SET job = ?, days = ?, time = ?, cron_expression = ? WHERE id = ?"
+	query = r.db.Rebind(query)
+	_, err := r.db.ExecContext(ctx, query, &s.Job, &s.Days, &s.Time, &s.CronExpression, &s.ID)
+	return err
+}
+
+func (r repo) DeleteSchedule(ctx context.Context, id int64) error {
+	query := "DELETE FROM schedules WHERE id = ?"
+	query = r.db.Rebind(query)
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+func (r repo) UseNonce(ctx context.Context, nonce string, expiry time.Duration) (bool, error) {
+	query := "DELETE FROM cron_nonces WHERE created_at < now() - make_interval(secs => ?)"
+	query = r.db.Rebind(query)
+	if _, err := r.db.ExecContext(ctx, query, expiry.Seconds()); err != nil {
+		return false, err
+	}
+
+	query = "INSERT INTO cron_nonces(nonce) VALUES (?) ON CONFLICT DO NOTHING"
+	query = r.db.Rebind(query)
+	res, err := r.db.ExecContext(ctx, query, nonce)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
@@ -0,0 +1,212 @@
+package cron
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/alvinhuhhh/go-alfred/internal/config"
+	"github.com/jmoiron/sqlx"
+)
+
+// leaderLockKey is the Postgres advisory lock held by the replica that fires in-process schedules
+const leaderLockKey int64 = 0x616c66726564 // "alfred"
+
+// Scheduler keeps the backing jobs of schedules in sync with the schedules table.
+type Scheduler interface {
+	Schedule(ctx context.Context, sc *Schedule) error
+	Unschedule(ctx context.Context, sc *Schedule) error
+}
+
+// JobRunner runs the job of a schedule, as /api/cron would.
+type JobRunner interface {
+	RunJob(ctx context.Context, chatId int64, job string) error
+}
+
+// PgCronScheduler creates a pg_cron job per schedule which calls /api/cron.
+// It requires the pg_cron and pg_net extensions, which are available on Supabase.
+type PgCronScheduler struct {
+	repo Repo
+}
+
+func NewPgCronScheduler(r Repo) (*PgCronScheduler, error) {
+	return &PgCronScheduler{repo: r}, nil
+}
+
+func (s *PgCronScheduler) Schedule(ctx context.Context, sc *Schedule) error {
+	url, err := config.GetPublicUrl()
+	if err != nil {
+		return err
+	}
+	return s.repo.Schedule(ctx, sc.JobName(), sc.CronExpression, url+"/api/cron", sc.ChatID, sc.Job)
+}
+
+func (s *PgCronScheduler) Unschedule(ctx context.Context, sc *Schedule) error {
+	return s.repo.Unschedule(ctx, sc.JobName())
+}
+
+// Start stores the current cron secret for the pg_cron jobs to sign with and registers every job again,
+// then keeps the jobs in step with daylight saving until ctx is done. pg_cron runs in UTC, so the
+// expressions are checked at the start of every hour, when clocks change.
+func (s *PgCronScheduler) Start(ctx context.Context) {
+	secret, err := config.GetCronSecret()
+	if err != nil {
+		slog.Error(err.Error())
+	} else if err := s.repo.SaveCronSecret(ctx, secret); err != nil {
+		slog.Error(err.Error())
+	}
+	slog.Info("pg_cron scheduler started")
+	s.sync(ctx, time.Now(), true)
+
+	for {
+		now := time.Now()
+		next := now.Truncate(time.Hour).Add(time.Hour)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(next.Sub(now)):
+			s.sync(ctx, next, false)
+		}
+	}
+}
+
+// sync updates the schedules whose cron expression has changed with their chat's UTC offset. With all,
+// every job is registered again, so that jobs made by an older version run the current command.
+func (s *PgCronScheduler) sync(ctx context.Context, at time.Time, all bool) {
+	schedules, err := s.repo.GetAllSchedules(ctx)
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+	for _, sc := range schedules {
+		expr, ok, err := sc.expressionAt(at)
+		if err != nil {
+			slog.Error(fmt.Sprintf("unable to sync schedule id %d: %s", sc.ID, err.Error()))
+			continue
+		}
+		moved := ok && expr != sc.CronExpression
+		if !moved && !all {
+			continue
+		}
+		if moved {
+			sc.CronExpression = expr
+			if err := s.repo.UpdateSchedule(ctx, &sc); err != nil {
+				slog.Error(err.Error())
+				continue
+			}
+			slog.Info(fmt.Sprintf("moved schedule id %d to %s for daylight saving", sc.ID, expr))
+		}
+		if err := s.Schedule(ctx, &sc); err != nil {
+			slog.Error(err.Error())
+		}
+	}
+}
+
+// InProcessScheduler reads the schedules table every minute and runs due jobs itself, so it works
+// against a plain Postgres. Only the replica holding a Postgres advisory lock fires jobs.
+type InProcessScheduler struct {
+	db     *sqlx.DB
+	repo   Repo
+	runner JobRunner
+	leader *sql.Conn
+}
+
+func NewInProcessScheduler(db *sqlx.DB, r Repo, runner JobRunner) (*InProcessScheduler, error) {
+	return &InProcessScheduler{
+		db:     db,
+		repo:   r,
+		runner: runner,
+	}, nil
+}
+
+// Schedules are read from the database on every tick, so there is nothing to register
+func (s *InProcessScheduler) Schedule(ctx context.Context, sc *Schedule) error {
+	return nil
+}
+
+func (s *InProcessScheduler) Unschedule(ctx context.Context, sc *Schedule) error {
+	return nil
+}
+
+// Start runs due schedules at the start of every minute until ctx is done.
+func (s *InProcessScheduler) Start(ctx context.Context) {
+	slog.Info("In-process scheduler started")
+	defer s.resign()
+
+	for {
+		now := time.Now()
+		next := now.Truncate(time.Minute).Add(time.Minute)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(next.Sub(now)):
+			s.tick(ctx, next)
+		}
+	}
+}
+
+func (s *InProcessScheduler) tick(ctx context.Context, at time.Time) {
+	if !s.elect(ctx) {
+		return
+	}
+
+	schedules, err := s.repo.GetAllSchedules(ctx)
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+	for _, sc := range schedules {
+		due, err := sc.Due(at)
+		if err != nil {
+			slog.Error(fmt.Sprintf("invalid timing for schedule id %d: %s", sc.ID, err.Error()))
+			continue
+		}
+		if !due {
+			continue
+		}
+		slog.Info(fmt.Sprintf("running schedule id %d: %s for chat id %d", sc.ID, sc.Job, sc.ChatID))
+		if err := s.runner.RunJob(ctx, sc.ChatID, sc.Job); err != nil {
+			slog.Error(err.Error())
+		}
+	}
+}
+
+// elect reports whether this replica is the leader, trying to become one if it is not.
+// Advisory locks belong to a session, so the lock is held on a dedicated connection.
+func (s *InProcessScheduler) elect(ctx context.Context) bool {
+	if s.leader != nil {
+		if err := s.leader.PingContext(ctx); err == nil {
+			return true
+		}
+		slog.Warn("lost scheduler leader connection")
+		s.resign()
+	}
+
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		slog.Error(err.Error())
+		return false
+	}
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", leaderLockKey).Scan(&acquired); err != nil || !acquired {
+		if err != nil {
+			slog.Error(err.Error())
+		}
+		conn.Close()
+		return false
+	}
+	slog.Info("elected as scheduler leader")
+	s.leader = conn
+	return true
+}
+
+func (s *InProcessScheduler) resign() {
+	if s.leader == nil {
+		return
+	}
+	// Closing the session releases the advisory lock
+	s.leader.Close()
+	s.leader = nil
+}
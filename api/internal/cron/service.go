@@ -0,0 +1,720 @@
+package cron
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alvinhuhhh/go-alfred/internal/chat"
+	"github.com/alvinhuhhh/go-alfred/internal/dinner"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+)
+
+// jobs maps the job names users type to the jobs understood by /api/cron
+var jobs = map[string]string{
+	"dinner":   dinner.JobDinner,
+	"reminder": dinner.JobMaybeReminder,
+	"dm":       dinner.JobDMReminder,
+	"lock":     dinner.JobLockDinner,
+}
+
+// jobNames returns the job names users can type, in alphabetical order.
+func jobNames() []string {
+	return slices.Sorted(maps.Keys(jobs))
+}
+
+// nudgeSchedule is how often chats with nudges turned on are checked for a nudge that is due
+const nudgeSchedule = "*/5 * * * *"
+
+// minNudgeInterval matches nudgeSchedule, as nudges can't be sent more often than they are checked
+const minNudgeInterval = 5 * time.Minute
+
+type Service interface {
+	HandleSchedule(ctx context.Context, b *bot.Bot, update *models.Update)
+	HandleNudge(ctx context.Context, b *bot.Bot, update *models.Update)
+	HandleSettings(ctx context.Context, b *bot.Bot, update *models.Update)
+}
+
+type service struct {
+	repo      Repo
+	chatRepo  chat.Repo
+	scheduler Scheduler
+}
+
+func NewService(r Repo, cr chat.Repo, sc Scheduler) (Service, error) {
+	return &service{
+		repo:      r,
+		chatRepo:  cr,
+		scheduler: sc,
+	}, nil
+}
+
+func (s service) HandleSchedule(ctx context.Context, b *bot.Bot, update *models.Update) {
+	chatId := update.Message.Chat.ID
+	args := strings.Fields(update.Message.Text)
+	command, _, _ := strings.Cut(args[0], "@")
+
+	c, err := s.chatRepo.GetChatByID(ctx, chatId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "Please /start me first!",
+			})
+			return
+		}
+		slog.Error(err.Error())
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatId,
+			Text:   "Sorry! Having a bit of trouble, will be back soon!",
+		})
+		return
+	}
+
+	switch command {
+	case "/schedules":
+		schedules, err := s.repo.GetSchedulesForChatId(ctx, chatId)
+		if err != nil {
+			slog.Error(err.Error())
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "Sorry! Having a bit of trouble, will be back soon!",
+			})
+			return
+		}
+		if len(schedules) == 0 {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "Nothing scheduled yet! Try /schedule dinner mon-fri 16:00",
+			})
+			return
+		}
+		lines := []string{}
+		for _, sc := range schedules {
+			line := fmt.Sprintf("#%d %s %s %s", sc.ID, sc.Job, sc.Days, sc.Time)
+			if command := managedBy(&sc, c); command != "" {
+				line += fmt.Sprintf(" (change it with %s)", command)
+			}
+			lines = append(lines, line)
+		}
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatId,
+			Text:   fmt.Sprintf("Scheduled jobs:\n%s\n\nChange one with /reschedule <id> <days> <time> or remove it with /unschedule <id>", strings.Join(lines, "\n")),
+		})
+
+	case "/schedule":
+		if len(args) < 4 {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "Tell me what to schedule, e.g. /schedule dinner mon-fri 16:00\n\nJobs: " + strings.Join(jobNames(), ", "),
+			})
+			return
+		}
+		job, found := jobs[strings.ToLower(args[1])]
+		if !found {
+			names := jobNames()
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   fmt.Sprintf("Sorry, I can only schedule %s or %s", strings.Join(names[:len(names)-1], ", "), names[len(names)-1]),
+			})
+			return
+		}
+		sc := &Schedule{ChatID: chatId, Job: job}
+		if command := managedBy(sc, c); command != "" {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   fmt.Sprintf("Dinner already locks at %s every day. Change it with %s", c.DinnerCutoff, command),
+			})
+			return
+		}
+		if err := s.setTiming(sc, c, args[2], args[3]); err != nil {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "Sorry, I don't understand that schedule. Try something like /schedule dinner mon-fri 16:00",
+			})
+			return
+		}
+		id, err := s.repo.InsertSchedule(ctx, sc)
+		if err != nil {
+			slog.Error(err.Error())
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "Sorry! Having a bit of trouble, will be back soon!",
+			})
+			return
+		}
+		sc.ID = id
+		if err := s.scheduler.Schedule(ctx, sc); err != nil {
+			slog.Error(err.Error())
+			s.repo.DeleteSchedule(ctx, id)
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "Sorry! Having a bit of trouble, will be back soon!",
+			})
+			return
+		}
+		slog.Info(fmt.Sprintf("inserted schedule id: %v", id))
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatId,
+			Text:   fmt.Sprintf("Done! I'll run %s on %s at %s (#%d)", sc.Job, sc.Days, sc.Time, sc.ID),
+		})
+
+	case "/reschedule":
+		if len(args) < 4 {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "Tell me what to change, e.g. /reschedule 1 mon-fri 17:00",
+			})
+			return
+		}
+		sc, err := s.getScheduleForChat(ctx, args[1], chatId)
+		if err != nil {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "Sorry, I can't find that schedule. Check /schedules for the list",
+			})
+			return
+		}
+		if command := managedBy(sc, c); command != "" {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   fmt.Sprintf("#%d is kept in step with %s, please change it there", sc.ID, command),
+			})
+			return
+		}
+		if err := s.setTiming(sc, c, args[2], args[3]); err != nil {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "Sorry, I don't understand that schedule. Try something like /reschedule 1 mon-fri 17:00",
+			})
+			return
+		}
+		if err := s.repo.UpdateSchedule(ctx, sc); err != nil {
+			slog.Error(err.Error())
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "Sorry! Having a bit of trouble, will be back soon!",
+			})
+			return
+		}
+		if err := s.scheduler.Schedule(ctx, sc); err != nil {
+			slog.Error(err.Error())
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "Sorry! Having a bit of trouble, will be back soon!",
+			})
+			return
+		}
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatId,
+			Text:   fmt.Sprintf("Done! I'll run %s on %s at %s (#%d)", sc.Job, sc.Days, sc.Time, sc.ID),
+		})
+
+	case "/unschedule":
+		if len(args) < 2 {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "Tell me which schedule to remove, e.g. /unschedule 1",
+			})
+			return
+		}
+		sc, err := s.getScheduleForChat(ctx, args[1], chatId)
+		if err != nil {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "Sorry, I can't find that schedule. Check /schedules for the list",
+			})
+			return
+		}
+		if command := managedBy(sc, c); command != "" {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   fmt.Sprintf("#%d is kept in step with %s, please change it there", sc.ID, command),
+			})
+			return
+		}
+		if err := s.scheduler.Unschedule(ctx, sc); err != nil {
+			slog.Error(err.Error())
+		}
+		if err := s.repo.DeleteSchedule(ctx, sc.ID); err != nil {
+			slog.Error(err.Error())
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "Sorry! Having a bit of trouble, will be back soon!",
+			})
+			return
+		}
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatId,
+			Text:   fmt.Sprintf("Done! #%d is no longer scheduled", sc.ID),
+		})
+
+	default:
+		slog.Error("unknown command")
+	}
+}
+
+// HandleNudge turns nudges about unanswered dinners on or off, and sets the quiet hours in which
+// they are held back. Nudges are checked by a schedule which is kept alongside the setting.
+func (s service) HandleNudge(ctx context.Context, b *bot.Bot, update *models.Update) {
+	chatId := update.Message.Chat.ID
+	args := strings.Fields(update.Message.Text)
+	command, _, _ := strings.Cut(args[0], "@")
+
+	c, err := s.chatRepo.GetChatByID(ctx, chatId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "Please /start me first!",
+			})
+			return
+		}
+		slog.Error(err.Error())
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatId,
+			Text:   "Sorry! Having a bit of trouble, will be back soon!",
+		})
+		return
+	}
+
+	var text string
+	switch command {
+	case "/nudge":
+		if len(args) < 2 {
+			text = "Nudges are off. Turn them on with e.g. /nudge 30m, and I'll mention everyone who hasn't answered dinner every 30 minutes until the cutoff"
+			if c.NudgeInterval > 0 {
+				text = fmt.Sprintf("I mention everyone who hasn't answered dinner every %s until the cutoff. Turn it off with /nudge off", formatInterval(c.NudgeInterval))
+			}
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   text,
+			})
+			return
+		}
+		if strings.ToLower(args[1]) == "off" {
+			c.NudgeInterval = 0
+			text = "Done! I'll stop nudging about dinner"
+		} else {
+			interval, err := parseNudgeInterval(args[1])
+			if err != nil {
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID: chatId,
+					Text:   fmt.Sprintf("Sorry, I don't understand that. Try something like /nudge 30m, at least every %s", formatInterval(int(minNudgeInterval.Minutes()))),
+				})
+				return
+			}
+			c.NudgeInterval = interval
+			text = fmt.Sprintf("Done! I'll mention everyone who hasn't answered dinner every %s until the cutoff", formatInterval(interval))
+			if c.QuietHoursStart != "" {
+				text += fmt.Sprintf(", except between %s and %s", c.QuietHoursStart, c.QuietHoursEnd)
+			}
+		}
+		if err := s.chatRepo.UpdateChat(ctx, c); err != nil {
+			slog.Error(err.Error())
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "Sorry! Having a bit of trouble, will be back soon!",
+			})
+			return
+		}
+		if err := s.syncNudgeSchedule(ctx, chatId, c.NudgeInterval > 0); err != nil {
+			slog.Error(err.Error())
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "Sorry! Having a bit of trouble, will be back soon!",
+			})
+			return
+		}
+
+	case "/quiethours":
+		if len(args) < 2 {
+			text = "There are no quiet hours. Set them with e.g. /quiethours 22:00-08:00"
+			if c.QuietHoursStart != "" {
+				text = fmt.Sprintf("Quiet hours are %s to %s, when I won't nudge anyone. Turn them off with /quiethours off", c.QuietHoursStart, c.QuietHoursEnd)
+			}
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   text,
+			})
+			return
+		}
+		if strings.ToLower(args[1]) == "off" {
+			c.QuietHoursStart, c.QuietHoursEnd = "", ""
+			text = "Done! There are no quiet hours"
+		} else {
+			start, end, err := parseQuietHours(args[1])
+			if err != nil {
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID: chatId,
+					Text:   "Sorry, I don't understand those hours. Try something like /quiethours 22:00-08:00",
+				})
+				return
+			}
+			c.QuietHoursStart, c.QuietHoursEnd = start, end
+			text = fmt.Sprintf("Done! I won't nudge anyone between %s and %s", start, end)
+		}
+		if err := s.chatRepo.UpdateChat(ctx, c); err != nil {
+			slog.Error(err.Error())
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "Sorry! Having a bit of trouble, will be back soon!",
+			})
+			return
+		}
+
+	default:
+		slog.Error("unknown command")
+		return
+	}
+
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatId,
+		Text:   text,
+	})
+}
+
+// HandleSettings changes chat settings that decide when scheduled jobs run, keeping the schedules in step.
+func (s service) HandleSettings(ctx context.Context, b *bot.Bot, update *models.Update) {
+	chatId := update.Message.Chat.ID
+	args := strings.Fields(update.Message.Text)
+	command, _, _ := strings.Cut(args[0], "@")
+
+	c, err := s.chatRepo.GetChatByID(ctx, chatId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "Please /start me first!",
+			})
+			return
+		}
+		slog.Error(err.Error())
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatId,
+			Text:   "Sorry! Having a bit of trouble, will be back soon!",
+		})
+		return
+	}
+
+	var text string
+	switch command {
+	case "/dinnercutoff":
+		if len(args) < 2 {
+			text = "There is no dinner cutoff. Set one with e.g. /dinnercutoff 17:30"
+			if c.DinnerCutoff != "" {
+				text = fmt.Sprintf("Dinner locks at %s every day. Turn it off with /dinnercutoff off", c.DinnerCutoff)
+			}
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   text,
+			})
+			return
+		}
+		if strings.ToLower(args[1]) == "off" {
+			c.DinnerCutoff = ""
+			text = "Done! Dinner will no longer lock"
+		} else {
+			cutoff, err := time.Parse("15:04", args[1])
+			if err != nil {
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID: chatId,
+					Text:   "Sorry, I don't understand that time. Try something like /dinnercutoff 17:30",
+				})
+				return
+			}
+			c.DinnerCutoff = cutoff.Format("15:04")
+			text = fmt.Sprintf("Done! Dinner will lock at %s every day", c.DinnerCutoff)
+		}
+		if err := s.chatRepo.UpdateChat(ctx, c); err != nil {
+			slog.Error(err.Error())
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "Sorry! Having a bit of trouble, will be back soon!",
+			})
+			return
+		}
+		if err := s.syncLockSchedule(ctx, c); err != nil {
+			slog.Error(err.Error())
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "Sorry! Having a bit of trouble, will be back soon!",
+			})
+			return
+		}
+
+	case "/timezone":
+		if len(args) < 2 {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   fmt.Sprintf("This chat is using the %s timezone. Change it with e.g. /timezone Asia/Singapore", c.Location().String()),
+			})
+			return
+		}
+		if _, err := time.LoadLocation(args[1]); err != nil || args[1] == "Local" {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "Sorry, I don't know that timezone. Try something like /timezone Asia/Singapore",
+			})
+			return
+		}
+		c.Timezone = args[1]
+		if err := s.chatRepo.UpdateChat(ctx, c); err != nil {
+			slog.Error(err.Error())
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "Sorry! Having a bit of trouble, will be back soon!",
+			})
+			return
+		}
+		if err := s.reschedule(ctx, c); err != nil {
+			slog.Error(err.Error())
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "Sorry! Having a bit of trouble, will be back soon!",
+			})
+			return
+		}
+		text = fmt.Sprintf("Done! This chat is now using the %s timezone", c.Timezone)
+
+	default:
+		slog.Error("unknown command")
+		return
+	}
+
+	b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID: chatId,
+		Text:   text,
+	})
+}
+
+// reschedule works out the cron expressions of a chat's schedules again, as they are stored in UTC
+// and change with the chat's timezone.
+func (s service) reschedule(ctx context.Context, c *chat.Chat) error {
+	schedules, err := s.repo.GetSchedulesForChatId(ctx, c.ID)
+	if err != nil {
+		return err
+	}
+	for i := range schedules {
+		sc := &schedules[i]
+		if sc.Job == dinner.JobNudge {
+			// Runs at a fixed interval, whatever the timezone
+			continue
+		}
+		if err := s.setTiming(sc, c, sc.Days, sc.Time); err != nil {
+			slog.Error(fmt.Sprintf("unable to reschedule #%d: %s", sc.ID, err))
+			continue
+		}
+		if err := s.repo.UpdateSchedule(ctx, sc); err != nil {
+			return err
+		}
+		if err := s.scheduler.Schedule(ctx, sc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncLockSchedule makes sure that a chat has a single daily lock schedule at its dinner cutoff, or none
+// when there is no cutoff.
+func (s service) syncLockSchedule(ctx context.Context, c *chat.Chat) error {
+	schedules, err := s.repo.GetSchedulesForChatId(ctx, c.ID)
+	if err != nil {
+		return err
+	}
+	var sc *Schedule
+	for i := range schedules {
+		if schedules[i].Job != dinner.JobLockDinner {
+			continue
+		}
+		if c.DinnerCutoff != "" && sc == nil {
+			sc = &schedules[i]
+			continue
+		}
+		if err := s.scheduler.Unschedule(ctx, &schedules[i]); err != nil {
+			slog.Error(err.Error())
+		}
+		if err := s.repo.DeleteSchedule(ctx, schedules[i].ID); err != nil {
+			return err
+		}
+	}
+	if c.DinnerCutoff == "" {
+		return nil
+	}
+
+	if sc != nil {
+		if err := s.setTiming(sc, c, "daily", c.DinnerCutoff); err != nil {
+			return err
+		}
+		if err := s.repo.UpdateSchedule(ctx, sc); err != nil {
+			return err
+		}
+		return s.scheduler.Schedule(ctx, sc)
+	}
+
+	sc = &Schedule{
+		ChatID: c.ID,
+		Job:    dinner.JobLockDinner,
+	}
+	if err := s.setTiming(sc, c, "daily", c.DinnerCutoff); err != nil {
+		return err
+	}
+	id, err := s.repo.InsertSchedule(ctx, sc)
+	if err != nil {
+		return err
+	}
+	sc.ID = id
+	if err := s.scheduler.Schedule(ctx, sc); err != nil {
+		s.repo.DeleteSchedule(ctx, id)
+		return err
+	}
+	slog.Info(fmt.Sprintf("inserted lock schedule id: %v", id))
+	return nil
+}
+
+// managedBy returns the command that looks after a schedule which is kept in step with a chat
+// setting, or "" for schedules that are set by hand.
+func managedBy(sc *Schedule, c *chat.Chat) string {
+	switch sc.Job {
+	case dinner.JobNudge:
+		return "/nudge"
+	case dinner.JobLockDinner:
+		// Without a cutoff, dinner can still be locked on a schedule of its own
+		if c.DinnerCutoff != "" {
+			return "/dinnercutoff"
+		}
+	}
+	return ""
+}
+
+// syncNudgeSchedule makes sure that a chat has a nudge schedule exactly when it has nudges turned on.
+func (s service) syncNudgeSchedule(ctx context.Context, chatId int64, enabled bool) error {
+	schedules, err := s.repo.GetSchedulesForChatId(ctx, chatId)
+	if err != nil {
+		return err
+	}
+	for _, sc := range schedules {
+		if sc.Job != dinner.JobNudge {
+			continue
+		}
+		if enabled {
+			return nil
+		}
+		if err := s.scheduler.Unschedule(ctx, &sc); err != nil {
+			slog.Error(err.Error())
+		}
+		if err := s.repo.DeleteSchedule(ctx, sc.ID); err != nil {
+			return err
+		}
+	}
+	if !enabled {
+		return nil
+	}
+
+	sc := &Schedule{
+		ChatID:         chatId,
+		Job:            dinner.JobNudge,
+		Days:           "daily",
+		Time:           "every " + formatInterval(int(minNudgeInterval.Minutes())),
+		CronExpression: nudgeSchedule,
+	}
+	id, err := s.repo.InsertSchedule(ctx, sc)
+	if err != nil {
+		return err
+	}
+	sc.ID = id
+	if err := s.scheduler.Schedule(ctx, sc); err != nil {
+		s.repo.DeleteSchedule(ctx, id)
+		return err
+	}
+	slog.Info(fmt.Sprintf("inserted nudge schedule id: %v", id))
+	return nil
+}
+
+// parseNudgeInterval parses a nudge interval such as "30m", "1h30m" or "45" (minutes) into minutes.
+func parseNudgeInterval(s string) (int, error) {
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		minutes, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, err
+		}
+		d = time.Duration(minutes) * time.Minute
+	}
+	if d < minNudgeInterval || d > 24*time.Hour {
+		return 0, fmt.Errorf("nudge interval out of range: %s", s)
+	}
+	return int(d.Minutes()), nil
+}
+
+// parseQuietHours parses quiet hours such as "22:00-08:00" into their start and end times.
+func parseQuietHours(s string) (string, string, error) {
+	from, to, found := strings.Cut(s, "-")
+	if !found {
+		return "", "", fmt.Errorf("invalid quiet hours: %s", s)
+	}
+	start, err := time.Parse("15:04", from)
+	if err != nil {
+		return "", "", err
+	}
+	end, err := time.Parse("15:04", to)
+	if err != nil {
+		return "", "", err
+	}
+	if start.Equal(end) {
+		return "", "", fmt.Errorf("quiet hours start and end at the same time: %s", s)
+	}
+	return start.Format("15:04"), end.Format("15:04"), nil
+}
+
+// formatInterval describes an interval in minutes, e.g. "30 minutes", "hour" or "1h30m".
+func formatInterval(minutes int) string {
+	switch {
+	case minutes < 60:
+		return fmt.Sprintf("%d minutes", minutes)
+	case minutes == 60:
+		return "hour"
+	case minutes%60 == 0:
+		return fmt.Sprintf("%d hours", minutes/60)
+	default:
+		return fmt.Sprintf("%dh%02dm", minutes/60, minutes%60)
+	}
+}
+
+// setTiming validates the days and time given by the user and stores them on the schedule.
+func (s service) setTiming(sc *Schedule, c *chat.Chat, days, clock string) error {
+	d, err := ParseDays(days)
+	if err != nil {
+		return err
+	}
+	expr, err := ToCronExpression(d, clock, c.Location(), time.Now())
+	if err != nil {
+		return err
+	}
+	sc.Days = strings.ToLower(days)
+	sc.Time = clock
+	sc.CronExpression = expr
+	return nil
+}
+
+func (s service) getScheduleForChat(ctx context.Context, id string, chatId int64) (*Schedule, error) {
+	scheduleId, err := strconv.ParseInt(strings.TrimPrefix(id, "#"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	sc, err := s.repo.GetScheduleById(ctx, scheduleId)
+	if err != nil {
+		return nil, err
+	}
+	if sc.ChatID != chatId {
+		return nil, sql.ErrNoRows
+	}
+	return sc, nil
+}
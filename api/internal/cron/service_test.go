@@ -0,0 +1,61 @@
+package cron
+
+import (
+	"testing"
+
+	"github.com/alvinhuhhh/go-alfred/internal/chat"
+	"github.com/alvinhuhhh/go-alfred/internal/dinner"
+)
+
+func Test_ParseNudgeInterval(t *testing.T) {
+	cases := map[string]int{"30m": 30, "1h30m": 90, "45": 45}
+	for input, expected := range cases {
+		actual, err := parseNudgeInterval(input)
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", input, err.Error())
+		}
+		if actual != expected {
+			t.Errorf("expected %d minutes for %q, got %d", expected, input, actual)
+		}
+	}
+	for _, input := range []string{"1m", "2d", "soon", "25h"} {
+		if _, err := parseNudgeInterval(input); err == nil {
+			t.Errorf("expected error for %q", input)
+		}
+	}
+}
+
+func Test_ParseQuietHours(t *testing.T) {
+	start, end, err := parseQuietHours("22:00-8:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if start != "22:00" || end != "08:00" {
+		t.Errorf("expected 22:00-08:00, got %s-%s", start, end)
+	}
+	if _, _, err := parseQuietHours("22:00"); err == nil {
+		t.Errorf("expected error for missing end")
+	}
+}
+
+func Test_ManagedBy(t *testing.T) {
+	c := &chat.Chat{NudgeInterval: 30}
+	cases := map[string]string{
+		dinner.JobNudge:  "/nudge",
+		dinner.JobDinner: "",
+	}
+	for job, expected := range cases {
+		if actual := managedBy(&Schedule{Job: job}, c); actual != expected {
+			t.Errorf("expected %q for %s, got %q", expected, job, actual)
+		}
+	}
+
+	lock := &Schedule{Job: dinner.JobLockDinner}
+	if actual := managedBy(lock, c); actual != "" {
+		t.Errorf("expected lock to be set by hand without a cutoff, got %q", actual)
+	}
+	c.DinnerCutoff = "17:30"
+	if actual := managedBy(lock, c); actual != "/dinnercutoff" {
+		t.Errorf("expected lock to be kept in step with /dinnercutoff, got %q", actual)
+	}
+}
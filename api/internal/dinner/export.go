@@ -0,0 +1,143 @@
+package dinner
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	ExportCSV = "csv"
+	ExportICS = "ics"
+)
+
+// exportRange parses the optional YYYY-MM-DD bounds of an export. By default it covers the past
+// year and the year ahead, so that subscribed calendars also show planned dinners.
+func exportRange(from, to string, today time.Time) (time.Time, time.Time, error) {
+	start, end := today.AddDate(-1, 0, 0), today.AddDate(1, 0, 0)
+	var err error
+	if from != "" {
+		if start, err = time.Parse("2006-01-02", from); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	if to != "" {
+		if end, err = time.Parse("2006-01-02", to); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	if end.Before(start) {
+		return time.Time{}, time.Time{}, fmt.Errorf("export range ends before it starts")
+	}
+	return start, end, nil
+}
+
+// exportContentType returns the MIME type of an export format.
+func exportContentType(format string) string {
+	if format == ExportICS {
+		return "text/calendar; charset=utf-8"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// writeCSV writes one row per response to each dinner.
+func writeCSV(w io.Writer, dinners []Dinner) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"date", "name", "status", "guests", "eta"}); err != nil {
+		return err
+	}
+	for _, d := range dinners {
+		for _, a := range d.Attendees {
+			row := []string{d.Date.Format("2006-01-02"), a.Name, string(a.Status), strconv.Itoa(a.Guests), a.ETA}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeICS writes an iCalendar feed with an all-day event for each dinner, listing who responded.
+func writeICS(w io.Writer, dinners []Dinner, now time.Time) error {
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:-//Alfred//Dinners//EN",
+		"CALSCALE:GREGORIAN",
+		"X-WR-CALNAME:Dinners",
+	}
+	for _, d := range dinners {
+		summary := fmt.Sprintf("Dinner (%d eating)", d.Headcount())
+		if d.IsClosed() {
+			summary = fmt.Sprintf("Dinner (%d eating, closed)", d.Headcount())
+		}
+
+		sections := []string{}
+		if d.CookName != "" {
+			sections = append(sections, "Cook: "+d.CookName)
+		}
+		if d.Menu != "" {
+			sections = append(sections, "Menu: "+d.Menu)
+		}
+		for _, status := range []Status{StatusYes, StatusLate, StatusTakeaway, StatusMaybe, StatusNo} {
+			names := []string{}
+			for _, a := range d.Attendees {
+				if a.Status == status {
+					names = append(names, a.Name+attendeeDetails(a))
+				}
+			}
+			if len(names) > 0 {
+				sections = append(sections, fmt.Sprintf("%s: %s", status, strings.Join(names, ", ")))
+			}
+		}
+
+		lines = append(lines,
+			"BEGIN:VEVENT",
+			fmt.Sprintf("UID:dinner-%d@alfred", d.ID),
+			"DTSTAMP:"+now.UTC().Format("20060102T150405Z"),
+			"DTSTART;VALUE=DATE:"+d.Date.Format("20060102"),
+			"DTEND;VALUE=DATE:"+d.Date.AddDate(0, 0, 1).Format("20060102"),
+			"SUMMARY:"+escapeICS(summary),
+			"DESCRIPTION:"+escapeICS(strings.Join(sections, "\n")),
+			"TRANSP:TRANSPARENT",
+			"END:VEVENT",
+		)
+	}
+	lines = append(lines, "END:VCALENDAR")
+
+	for _, line := range lines {
+		if _, err := io.WriteString(w, foldICS(line)+"\r\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// escapeICS escapes an iCalendar TEXT value.
+func escapeICS(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`).Replace(s)
+}
+
+// foldICS splits a content line into lines of at most 75 octets, as required by RFC 5545.
+func foldICS(line string) string {
+	var sb strings.Builder
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		sb.WriteString(line[:cut])
+		sb.WriteString("\r\n ")
+		line = line[cut:]
+		// Continuation lines start with a space
+		limit = 74
+	}
+	sb.WriteString(line)
+	return sb.String()
+}
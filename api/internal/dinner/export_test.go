@@ -0,0 +1,45 @@
+package dinner
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_FoldICS(t *testing.T) {
+	line := "DESCRIPTION:" + strings.Repeat("é", 60)
+	for _, l := range strings.Split(foldICS(line), "\r\n") {
+		if len(l) > 75 {
+			t.Errorf("line is %d octets long: %q", len(l), l)
+		}
+	}
+	if unfolded := strings.ReplaceAll(foldICS(line), "\r\n ", ""); unfolded != line {
+		t.Errorf("expected %q after unfolding, got %q", line, unfolded)
+	}
+}
+
+func Test_WriteICS(t *testing.T) {
+	dinners := []Dinner{{
+		ID:   7,
+		Date: time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC),
+		Attendees: []Attendee{
+			{Name: "Alice", Status: StatusYes, Guests: 1},
+			{Name: "Bob", Status: StatusNo},
+		},
+	}}
+	var sb strings.Builder
+	if err := writeICS(&sb, dinners, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"UID:dinner-7@alfred\r\n",
+		"DTSTART;VALUE=DATE:20261018\r\n",
+		"DTEND;VALUE=DATE:20261019\r\n",
+		"SUMMARY:Dinner (2 eating)\r\n",
+		`DESCRIPTION:YES: Alice (+1)\nNO: Bob` + "\r\n",
+	} {
+		if !strings.Contains(sb.String(), expected) {
+			t.Errorf("expected feed to contain %q, got:\n%s", expected, sb.String())
+		}
+	}
+}
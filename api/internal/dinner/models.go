@@ -1,16 +1,121 @@
 package dinner
 
 import (
+	"slices"
 	"time"
 
 	"github.com/lib/pq"
 )
 
+type Status string
+
+const (
+	StatusYes      Status = "YES"
+	StatusNo       Status = "NO"
+	StatusMaybe    Status = "MAYBE"
+	StatusLate     Status = "LATE"
+	StatusTakeaway Status = "TAKEAWAY"
+)
+
+// EatingStatuses are the statuses of people that food needs to be prepared for.
+var EatingStatuses = []Status{StatusYes, StatusLate, StatusTakeaway}
+
+// IsEating reports whether food needs to be prepared for someone with this status.
+func (s Status) IsEating() bool {
+	return slices.Contains(EatingStatuses, s)
+}
+
 type Dinner struct {
-	ID         int64         `db:"id"`
-	Date       time.Time     `db:"date"`
-	ChatID     int64         `db:"chat_id"`
-	Yes        []string      `db:"yes"`
-	No         []string      `db:"no"`
-	MessageIds pq.Int64Array `db:"message_ids"`
+	ID         int64         `db:"id" json:"id"`
+	Date       time.Time     `db:"date" json:"date"`
+	ChatID     int64         `db:"chat_id" json:"chatId"`
+	MessageIds pq.Int64Array `db:"message_ids" json:"-"`
+	LockedAt   *time.Time    `db:"locked_at" json:"lockedAt"`
+	Reopened   bool          `db:"reopened" json:"reopened"`
+	ClosedAt   *time.Time    `db:"closed_at" json:"closedAt"`
+	Menu       string        `db:"menu" json:"menu"`
+	CookID     *int64        `db:"cook_id" json:"cookId"`
+	CookName   string        `db:"cook_name" json:"cookName"`
+	Attendees  []Attendee    `db:"-" json:"attendees"`
+}
+
+// IsClosed reports whether the dinner has been ended. Closed dinners keep their final list.
+func (d Dinner) IsClosed() bool {
+	return d.ClosedAt != nil
+}
+
+// PollOptions are the statuses offered on a dinner's native poll, in the order of its options.
+var PollOptions = []Status{StatusYes, StatusLate, StatusTakeaway, StatusMaybe, StatusNo}
+
+// Poll is a native Telegram poll posted for a dinner.
+type Poll struct {
+	ID        string    `db:"id" json:"id"`
+	DinnerID  int64     `db:"dinner_id" json:"dinnerId"`
+	MessageID int64     `db:"message_id" json:"messageId"`
+	CreatedAt time.Time `db:"created_at" json:"createdAt"`
+}
+
+type Attendee struct {
+	DinnerID  int64     `db:"dinner_id" json:"dinnerId"`
+	UserID    int64     `db:"user_id" json:"userId"`
+	Name      string    `db:"name" json:"name"`
+	Status    Status    `db:"status" json:"status"`
+	Guests    int       `db:"guests" json:"guests"`
+	ETA       string    `db:"eta" json:"eta"`
+	UpdatedAt time.Time `db:"updated_at" json:"updatedAt"`
+}
+
+// Attendee returns the response of the given user, or nil if they have not responded.
+func (d Dinner) Attendee(userId int64) *Attendee {
+	for i := range d.Attendees {
+		if d.Attendees[i].UserID == userId {
+			return &d.Attendees[i]
+		}
+	}
+	return nil
+}
+
+// Headcount returns the number of people eating, including guests brought along.
+func (d Dinner) Headcount() int {
+	count := 0
+	for _, a := range d.Attendees {
+		if a.Status.IsEating() {
+			count += 1 + a.Guests
+		}
+	}
+	return count
+}
+
+// Stats summarises the dinners of a chat over a period.
+type Stats struct {
+	From     time.Time      `json:"from"`
+	To       time.Time      `json:"to"`
+	Dinners  int            `json:"dinners"`
+	People   []PersonStats  `json:"people"`
+	Cooks    []CookStats    `json:"cooks"`
+	Weekdays []WeekdayStats `json:"weekdays"`
+}
+
+// PersonStats is one person's attendance. Streaks count consecutive dinners attended.
+type PersonStats struct {
+	UserID         int64   `db:"user_id" json:"userId"`
+	Name           string  `db:"name" json:"name"`
+	Attended       int     `db:"attended" json:"attended"`
+	Responded      int     `db:"responded" json:"responded"`
+	AttendanceRate float64 `db:"-" json:"attendanceRate"`
+	CurrentStreak  int     `db:"current_streak" json:"currentStreak"`
+	LongestStreak  int     `db:"longest_streak" json:"longestStreak"`
+}
+
+// CookStats counts the dinners someone cooked.
+type CookStats struct {
+	UserID int64  `db:"user_id" json:"userId"`
+	Name   string `db:"name" json:"name"`
+	Cooked int    `db:"cooked" json:"cooked"`
+}
+
+type WeekdayStats struct {
+	Weekday   time.Weekday `db:"weekday" json:"weekday"`
+	Dinners   int          `db:"dinners" json:"dinners"`
+	Headcount int          `db:"headcount" json:"headcount"`
 }
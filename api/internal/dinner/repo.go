@@ -2,6 +2,8 @@ package dinner
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -11,9 +13,33 @@ import (
 type Repo interface {
 	GetDinnerById(ctx context.Context, id int64) (*Dinner, error)
 	GetDinnerByDateAndChatId(ctx context.Context, chatId int64, date time.Time) (*Dinner, error)
-	InsertDinner(ctx context.Context, d *Dinner) (int64, error)
-	UpdateDinner(ctx context.Context, d *Dinner) error
-	DeleteDinner(ctx context.Context, id int64) error
+	UpsertDinner(ctx context.Context, chatId int64, date time.Time) (*Dinner, bool, error)
+	AppendMessageId(ctx context.Context, id int64, messageId int64) (pq.Int64Array, error)
+	RemoveMessageIds(ctx context.Context, id int64, messageIds []int64) (pq.Int64Array, error)
+	LockDinner(ctx context.Context, id int64) error
+	UnlockDinner(ctx context.Context, id int64) error
+	CloseDinner(ctx context.Context, id int64) error
+	ReopenDinner(ctx context.Context, id int64) error
+	SetMenu(ctx context.Context, id int64, menu string) error
+	ClaimNudge(ctx context.Context, id int64, interval time.Duration) (bool, error)
+	LockMessages(ctx context.Context, id int64) (func(), error)
+	SetCook(ctx context.Context, id int64, cookId *int64, cookName string) error
+	GetAttendees(ctx context.Context, dinnerId int64) ([]Attendee, error)
+	SetAttendeeStatus(ctx context.Context, dinnerId, userId int64, name string, status Status) error
+	SetAttendeeGuests(ctx context.Context, dinnerId, userId int64, name string, guests int) error
+	SetAttendeeETA(ctx context.Context, dinnerId, userId int64, name string, eta string) error
+	DeleteAttendee(ctx context.Context, dinnerId, userId int64) error
+	UpdateAttendeeName(ctx context.Context, dinnerId, userId int64, name string) error
+	GetPoll(ctx context.Context, id string) (*Poll, error)
+	GetPolls(ctx context.Context, dinnerId int64) ([]Poll, error)
+	InsertPoll(ctx context.Context, p *Poll) error
+	DeletePolls(ctx context.Context, dinnerId int64) error
+	GetDinnersForChatId(ctx context.Context, chatId int64, limit, offset int) ([]Dinner, error)
+	GetUpcomingDinners(ctx context.Context, chatId int64, from time.Time) ([]Dinner, error)
+	GetDinnersBetween(ctx context.Context, chatId int64, from, to time.Time) ([]Dinner, error)
+	GetAttendanceStats(ctx context.Context, chatId int64, from, to time.Time) ([]PersonStats, error)
+	GetWeekdayStats(ctx context.Context, chatId int64, from, to time.Time) ([]WeekdayStats, error)
+	GetCookStats(ctx context.Context, chatId int64, from, to time.Time) ([]CookStats, error)
 }
 
 type repo struct {
@@ -25,10 +51,14 @@ func NewRepo(db *sqlx.DB) (Repo, error) {
 }
 
 func (r repo) GetDinnerById(ctx context.Context, id int64) (*Dinner, error) {
-	query := "SELECT id, chat_id, date, yes, no, message_ids FROM dinners WHERE id = ?"
+	query := "SELECT id, chat_id, date, message_ids, locked_at, reopened, closed_at, menu, cook_id, cook_name FROM dinners WHERE id = ?"
 	query = r.db.Rebind(query)
 	var d Dinner
-	err := r.db.QueryRowContext(ctx, query, id).Scan(&d.ID, &d.ChatID, &d.Date, pq.Array(&d.Yes), pq.Array(&d.No), &d.MessageIds)
+	err := r.db.QueryRowContext(ctx, query, id).Scan(&d.ID, &d.ChatID, &d.Date, &d.MessageIds, &d.LockedAt, &d.Reopened, &d.ClosedAt, &d.Menu, &d.CookID, &d.CookName)
+	if err != nil {
+		return nil, err
+	}
+	d.Attendees, err = r.GetAttendees(ctx, d.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -36,43 +66,396 @@ func (r repo) GetDinnerById(ctx context.Context, id int64) (*Dinner, error) {
 }
 
 func (r repo) GetDinnerByDateAndChatId(ctx context.Context, chatId int64, date time.Time) (*Dinner, error) {
-	query := "SELECT id, chat_id, date, yes, no, message_ids FROM dinners WHERE chat_id = ? AND date = ?"
+	query := "SELECT id, chat_id, date, message_ids, locked_at, reopened, closed_at, menu, cook_id, cook_name FROM dinners WHERE chat_id = ? AND date = ?"
 	query = r.db.Rebind(query)
 	var d Dinner
-	err := r.db.QueryRowContext(ctx, query, chatId, date.Format("2006-01-02")).Scan(&d.ID, &d.ChatID, &d.Date, pq.Array(&d.Yes), pq.Array(&d.No), &d.MessageIds)
+	err := r.db.QueryRowContext(ctx, query, chatId, date.Format("2006-01-02")).Scan(&d.ID, &d.ChatID, &d.Date, &d.MessageIds, &d.LockedAt, &d.Reopened, &d.ClosedAt, &d.Menu, &d.CookID, &d.CookName)
+	if err != nil {
+		return nil, err
+	}
+	d.Attendees, err = r.GetAttendees(ctx, d.ID)
 	if err != nil {
 		return nil, err
 	}
 	return &d, nil
 }
 
-func (r repo) InsertDinner(ctx context.Context, d *Dinner) (int64, error) {
-	query := "INSERT INTO dinners(chat_id, date, yes, no, message_ids) VALUES (?,?,?,?,?) RETURNING id"
+// UpsertDinner returns the chat's dinner on date, creating it if there is none yet, and whether it was created.
+func (r repo) UpsertDinner(ctx context.Context, chatId int64, date time.Time) (*Dinner, bool, error) {
+	// The no-op update makes the existing row's id come back on conflict
+	query := `
+		INSERT INTO dinners(chat_id, date, message_ids) VALUES (?,?,'{}')
+		ON CONFLICT (chat_id, date) DO UPDATE SET chat_id = EXCLUDED.chat_id
+		RETURNING id, xmax = 0
+	`
 	query = r.db.Rebind(query)
 	var id int64
-	err := r.db.QueryRowContext(ctx, query, &d.ChatID, d.Date.Format("2006-01-02"), pq.Array(&d.Yes), pq.Array(&d.No), &d.MessageIds).Scan(&id)
+	var inserted bool
+	err := r.db.QueryRowContext(ctx, query, chatId, date.Format("2006-01-02")).Scan(&id, &inserted)
+	if err != nil {
+		return nil, false, err
+	}
+	d, err := r.GetDinnerById(ctx, id)
 	if err != nil {
-		return -1, err
+		return nil, false, err
 	}
-	return id, nil
+	return d, inserted, nil
+}
+
+// AppendMessageId records a new live poll message, keeping the last 1000, and returns the live messages.
+func (r repo) AppendMessageId(ctx context.Context, id int64, messageId int64) (pq.Int64Array, error) {
+	query := `
+		UPDATE dinners SET message_ids = (message_ids || ?::bigint)[greatest(cardinality(message_ids) - 998, 1):]
+		WHERE id = ? RETURNING message_ids
+	`
+	query = r.db.Rebind(query)
+	var ids pq.Int64Array
+	err := r.db.QueryRowContext(ctx, query, messageId, id).Scan(&ids)
+	return ids, err
+}
+
+// RemoveMessageIds forgets poll messages that are gone, and returns the live messages.
+func (r repo) RemoveMessageIds(ctx context.Context, id int64, messageIds []int64) (pq.Int64Array, error) {
+	query := `
+		UPDATE dinners SET message_ids = ARRAY(
+			SELECT m FROM unnest(message_ids) WITH ORDINALITY AS u(m, i) WHERE m <> ALL(?) ORDER BY i
+		)
+		WHERE id = ? RETURNING message_ids
+	`
+	query = r.db.Rebind(query)
+	var ids pq.Int64Array
+	err := r.db.QueryRowContext(ctx, query, pq.Int64Array(messageIds), id).Scan(&ids)
+	return ids, err
+}
+
+func (r repo) LockDinner(ctx context.Context, id int64) error {
+	query := "UPDATE dinners SET locked_at = now(), reopened = false WHERE id = ? AND locked_at IS NULL"
+	query = r.db.Rebind(query)
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+func (r repo) UnlockDinner(ctx context.Context, id int64) error {
+	query := "UPDATE dinners SET locked_at = NULL, reopened = true WHERE id = ?"
+	query = r.db.Rebind(query)
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+func (r repo) CloseDinner(ctx context.Context, id int64) error {
+	query := "UPDATE dinners SET closed_at = now() WHERE id = ? AND closed_at IS NULL"
+	query = r.db.Rebind(query)
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+func (r repo) ReopenDinner(ctx context.Context, id int64) error {
+	query := "UPDATE dinners SET closed_at = NULL WHERE id = ?"
+	query = r.db.Rebind(query)
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+func (r repo) SetMenu(ctx context.Context, id int64, menu string) error {
+	query := "UPDATE dinners SET menu = ? WHERE id = ?"
+	query = r.db.Rebind(query)
+	_, err := r.db.ExecContext(ctx, query, menu, id)
+	return err
 }
 
-func (r repo) UpdateDinner(ctx context.Context, d *Dinner) error {
-	query := "UPDATE dinners SET yes = ?, no = ?, message_ids = ? WHERE id = ?"
+// SetCook assigns the cook of a dinner, or unassigns it if cookId is nil.
+func (r repo) SetCook(ctx context.Context, id int64, cookId *int64, cookName string) error {
+	query := "UPDATE dinners SET cook_id = ?, cook_name = ? WHERE id = ?"
 	query = r.db.Rebind(query)
-	_, err := r.db.ExecContext(ctx, query, pq.Array(&d.Yes), pq.Array(&d.No), &d.MessageIds, &d.ID)
+	_, err := r.db.ExecContext(ctx, query, cookId, cookName, id)
+	return err
+}
+
+// ClaimNudge records that absent members are being nudged about a dinner, unless they were nudged within
+// the interval. It reports whether the nudge should be sent, so that overlapping runs only nudge once.
+func (r repo) ClaimNudge(ctx context.Context, id int64, interval time.Duration) (bool, error) {
+	// Allow a minute of slack, as runs are only minute-accurate
+	query := `
+		UPDATE dinners SET nudged_at = now()
+		WHERE id = ? AND (nudged_at IS NULL OR nudged_at <= now() - make_interval(mins => ?) + interval '1 minute')
+	`
+	query = r.db.Rebind(query)
+	res, err := r.db.ExecContext(ctx, query, id, int(interval.Minutes()))
 	if err != nil {
-		return err
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// LockMessages waits for and takes a Postgres advisory lock on the messages of a dinner, so that only one
+// replica refreshes them at a time. The lock belongs to a session, so it is held on a dedicated connection
+// until the returned function is called.
+func (r repo) LockMessages(ctx context.Context, id int64) (func(), error) {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("dinner_messages_%d", id)
+	if _, err := conn.ExecContext(ctx, r.db.Rebind("SELECT pg_advisory_lock(hashtextextended(?, 0))"), key); err != nil {
+		conn.Close()
+		return nil, err
 	}
-	return nil
+	return func() {
+		// Closing the session would release the lock too, but the connection goes back to the pool
+		if _, err := conn.ExecContext(context.Background(), r.db.Rebind("SELECT pg_advisory_unlock(hashtextextended(?, 0))"), key); err != nil {
+			slog.Error(err.Error())
+		}
+		conn.Close()
+	}, nil
 }
 
-func (r repo) DeleteDinner(ctx context.Context, id int64) error {
-	query := "DELETE FROM dinners WHERE id = ?"
+func (r repo) GetAttendees(ctx context.Context, dinnerId int64) ([]Attendee, error) {
+	query := "SELECT dinner_id, user_id, name, status, guests, eta, updated_at FROM dinner_attendees WHERE dinner_id = ? ORDER BY updated_at, user_id"
 	query = r.db.Rebind(query)
-	_, err := r.db.ExecContext(ctx, query, id)
+	a := []Attendee{}
+	err := r.db.SelectContext(ctx, &a, query, dinnerId)
+	return a, err
+}
+
+// SetAttendeeStatus sets someone's response to a dinner. Saying no drops any guests.
+func (r repo) SetAttendeeStatus(ctx context.Context, dinnerId, userId int64, name string, status Status) error {
+	query := `
+		INSERT INTO dinner_attendees(dinner_id, user_id, name, status, updated_at) VALUES (?,?,?,?,now())
+		ON CONFLICT (dinner_id, user_id) DO UPDATE SET
+			name = EXCLUDED.name,
+			status = EXCLUDED.status,
+			guests = CASE WHEN EXCLUDED.status = 'NO' THEN 0 ELSE dinner_attendees.guests END,
+			updated_at = CASE WHEN dinner_attendees.status = EXCLUDED.status THEN dinner_attendees.updated_at ELSE EXCLUDED.updated_at END
+	`
+	return r.respond(ctx, dinnerId, name, query, dinnerId, userId, name, status)
+}
+
+// SetAttendeeGuests sets how many guests someone is bringing, signing them up if they were not eating.
+func (r repo) SetAttendeeGuests(ctx context.Context, dinnerId, userId int64, name string, guests int) error {
+	query := `
+		INSERT INTO dinner_attendees(dinner_id, user_id, name, status, guests, updated_at) VALUES (?,?,?,'YES',?,now())
+		ON CONFLICT (dinner_id, user_id) DO UPDATE SET
+			name = EXCLUDED.name,
+			guests = EXCLUDED.guests,
+			status = CASE WHEN dinner_attendees.status = ANY(?) THEN dinner_attendees.status ELSE EXCLUDED.status END,
+			updated_at = CASE WHEN dinner_attendees.status = ANY(?) THEN dinner_attendees.updated_at ELSE EXCLUDED.updated_at END
+	`
+	eating := eatingStatuses()
+	return r.respond(ctx, dinnerId, name, query, dinnerId, userId, name, guests, eating, eating)
+}
+
+// SetAttendeeETA marks someone as coming late at the given time.
+func (r repo) SetAttendeeETA(ctx context.Context, dinnerId, userId int64, name string, eta string) error {
+	query := `
+		INSERT INTO dinner_attendees(dinner_id, user_id, name, status, eta, updated_at) VALUES (?,?,?,'LATE',?,now())
+		ON CONFLICT (dinner_id, user_id) DO UPDATE SET
+			name = EXCLUDED.name,
+			status = EXCLUDED.status,
+			eta = EXCLUDED.eta,
+			updated_at = CASE WHEN dinner_attendees.status = EXCLUDED.status THEN dinner_attendees.updated_at ELSE EXCLUDED.updated_at END
+	`
+	return r.respond(ctx, dinnerId, name, query, dinnerId, userId, name, eta)
+}
+
+// respond runs a single-statement upsert of a response, so that concurrent responses cannot overwrite
+// each other. Any placeholder carried over from the name-only schema is replaced in the same transaction.
+func (r repo) respond(ctx context.Context, dinnerId int64, name string, query string, args ...any) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return err
 	}
-	return nil
-}
\ No newline at end of file
+	defer tx.Rollback()
+
+	placeholder := r.db.Rebind("DELETE FROM dinner_attendees WHERE dinner_id = ? AND user_id < 0 AND name = ?")
+	if _, err := tx.ExecContext(ctx, placeholder, dinnerId, name); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, r.db.Rebind(query), args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeleteAttendee removes someone's response to a dinner, e.g. when they retract their poll vote.
+func (r repo) DeleteAttendee(ctx context.Context, dinnerId, userId int64) error {
+	query := "DELETE FROM dinner_attendees WHERE dinner_id = ? AND user_id = ?"
+	query = r.db.Rebind(query)
+	_, err := r.db.ExecContext(ctx, query, dinnerId, userId)
+	return err
+}
+
+// UpdateAttendeeName updates a user's name on one dinner, leaving past dinners and other chats as they were.
+func (r repo) UpdateAttendeeName(ctx context.Context, dinnerId, userId int64, name string) error {
+	query := "UPDATE dinner_attendees SET name = ? WHERE dinner_id = ? AND user_id = ? AND name <> ?"
+	query = r.db.Rebind(query)
+	_, err := r.db.ExecContext(ctx, query, name, dinnerId, userId, name)
+	return err
+}
+
+func (r repo) GetPoll(ctx context.Context, id string) (*Poll, error) {
+	query := "SELECT id, dinner_id, message_id, created_at FROM dinner_polls WHERE id = ?"
+	query = r.db.Rebind(query)
+	var p Poll
+	if err := r.db.GetContext(ctx, &p, query, id); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// GetPolls returns the live native polls of a dinner, oldest first.
+func (r repo) GetPolls(ctx context.Context, dinnerId int64) ([]Poll, error) {
+	query := "SELECT id, dinner_id, message_id, created_at FROM dinner_polls WHERE dinner_id = ? ORDER BY created_at"
+	query = r.db.Rebind(query)
+	p := []Poll{}
+	err := r.db.SelectContext(ctx, &p, query, dinnerId)
+	return p, err
+}
+
+func (r repo) InsertPoll(ctx context.Context, p *Poll) error {
+	query := "INSERT INTO dinner_polls(id, dinner_id, message_id) VALUES (?,?,?)"
+	query = r.db.Rebind(query)
+	_, err := r.db.ExecContext(ctx, query, p.ID, p.DinnerID, p.MessageID)
+	return err
+}
+
+func (r repo) DeletePolls(ctx context.Context, dinnerId int64) error {
+	query := "DELETE FROM dinner_polls WHERE dinner_id = ?"
+	query = r.db.Rebind(query)
+	_, err := r.db.ExecContext(ctx, query, dinnerId)
+	return err
+}
+
+func (r repo) GetDinnersForChatId(ctx context.Context, chatId int64, limit, offset int) ([]Dinner, error) {
+	query := "SELECT id, chat_id, date, message_ids, locked_at, reopened, closed_at, menu, cook_id, cook_name FROM dinners WHERE chat_id = ? ORDER BY date DESC LIMIT ? OFFSET ?"
+	query = r.db.Rebind(query)
+	d := []Dinner{}
+	if err := r.db.SelectContext(ctx, &d, query, chatId, limit, offset); err != nil {
+		return nil, err
+	}
+	for i := range d {
+		a, err := r.GetAttendees(ctx, d[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		d[i].Attendees = a
+	}
+	return d, nil
+}
+
+func (r repo) GetUpcomingDinners(ctx context.Context, chatId int64, from time.Time) ([]Dinner, error) {
+	query := "SELECT id, chat_id, date, message_ids, locked_at, reopened, closed_at, menu, cook_id, cook_name FROM dinners WHERE chat_id = ? AND date >= ? ORDER BY date ASC"
+	query = r.db.Rebind(query)
+	d := []Dinner{}
+	if err := r.db.SelectContext(ctx, &d, query, chatId, from.Format("2006-01-02")); err != nil {
+		return nil, err
+	}
+	for i := range d {
+		a, err := r.GetAttendees(ctx, d[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		d[i].Attendees = a
+	}
+	return d, nil
+}
+
+func (r repo) GetDinnersBetween(ctx context.Context, chatId int64, from, to time.Time) ([]Dinner, error) {
+	query := "SELECT id, chat_id, date, message_ids, locked_at, reopened, closed_at, menu, cook_id, cook_name FROM dinners WHERE chat_id = ? AND date BETWEEN ? AND ? ORDER BY date ASC"
+	query = r.db.Rebind(query)
+	d := []Dinner{}
+	if err := r.db.SelectContext(ctx, &d, query, chatId, from.Format("2006-01-02"), to.Format("2006-01-02")); err != nil {
+		return nil, err
+	}
+	for i := range d {
+		a, err := r.GetAttendees(ctx, d[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		d[i].Attendees = a
+	}
+	return d, nil
+}
+
+// GetAttendanceStats aggregates each person's responses to the chat's dinners between from and to,
+// most frequent attendees first. Streaks are runs of consecutive dinners attended.
+func (r repo) GetAttendanceStats(ctx context.Context, chatId int64, from, to time.Time) ([]PersonStats, error) {
+	query := `
+		WITH d AS (
+			SELECT id, date, row_number() OVER (ORDER BY date) AS n
+			FROM dinners WHERE chat_id = ? AND date BETWEEN ? AND ?
+		), a AS (
+			SELECT a.user_id, a.name, a.status, d.date, d.n
+			FROM d JOIN dinner_attendees a ON a.dinner_id = d.id
+		), streaks AS (
+			SELECT user_id, count(*) AS length, max(n) AS last
+			FROM (
+				SELECT user_id, n, n - row_number() OVER (PARTITION BY user_id ORDER BY n) AS grp
+				FROM a WHERE status = ANY(?)
+			) s
+			GROUP BY user_id, grp
+		)
+		SELECT
+			a.user_id,
+			(array_agg(a.name ORDER BY a.date DESC))[1] AS name,
+			count(*) FILTER (WHERE a.status = ANY(?)) AS attended,
+			count(*) AS responded,
+			COALESCE((SELECT max(s.length) FROM streaks s WHERE s.user_id = a.user_id), 0) AS longest_streak,
+			COALESCE((SELECT s.length FROM streaks s WHERE s.user_id = a.user_id AND s.last = (SELECT max(n) FROM d)), 0) AS current_streak
+		FROM a
+		GROUP BY a.user_id
+		ORDER BY attended DESC, name
+	`
+	query = r.db.Rebind(query)
+	eating := eatingStatuses()
+	p := []PersonStats{}
+	if err := r.db.SelectContext(ctx, &p, query, chatId, from.Format("2006-01-02"), to.Format("2006-01-02"), eating, eating); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// GetWeekdayStats counts the chat's dinners between from and to and the people eating at them, per day of the week.
+func (r repo) GetWeekdayStats(ctx context.Context, chatId int64, from, to time.Time) ([]WeekdayStats, error) {
+	query := `
+		SELECT
+			EXTRACT(DOW FROM d.date)::int AS weekday,
+			count(DISTINCT d.id) AS dinners,
+			COALESCE(sum(1 + a.guests) FILTER (WHERE a.status = ANY(?)), 0) AS headcount
+		FROM dinners d LEFT JOIN dinner_attendees a ON a.dinner_id = d.id
+		WHERE d.chat_id = ? AND d.date BETWEEN ? AND ?
+		GROUP BY weekday
+		ORDER BY weekday
+	`
+	query = r.db.Rebind(query)
+	w := []WeekdayStats{}
+	if err := r.db.SelectContext(ctx, &w, query, eatingStatuses(), chatId, from.Format("2006-01-02"), to.Format("2006-01-02")); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// GetCookStats counts the chat's dinners between from and to that each person cooked, busiest cooks first.
+func (r repo) GetCookStats(ctx context.Context, chatId int64, from, to time.Time) ([]CookStats, error) {
+	query := `
+		SELECT cook_id AS user_id, (array_agg(cook_name ORDER BY date DESC))[1] AS name, count(*) AS cooked
+		FROM dinners
+		WHERE chat_id = ? AND date BETWEEN ? AND ? AND cook_id IS NOT NULL
+		GROUP BY cook_id
+		ORDER BY cooked DESC, name
+	`
+	query = r.db.Rebind(query)
+	c := []CookStats{}
+	if err := r.db.SelectContext(ctx, &c, query, chatId, from.Format("2006-01-02"), to.Format("2006-01-02")); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func eatingStatuses() pq.StringArray {
+	statuses := pq.StringArray{}
+	for _, s := range EatingStatuses {
+		statuses = append(statuses, string(s))
+	}
+	return statuses
+}
@@ -0,0 +1,280 @@
+package dinner
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alvinhuhhh/go-alfred/internal/chat"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/jmoiron/sqlx"
+)
+
+// testDB connects to the database in TEST_DATABASE_URL and migrates it, skipping the test when it isn't set.
+func testDB(t *testing.T) *sqlx.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	db, err := sqlx.Open("pgx", url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	driver, err := postgres.WithInstance(db.DB, &postgres.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mig, err := migrate.NewWithDatabaseInstance("file://../../migrations", "postgres", driver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mig.Up(); err != nil && err != migrate.ErrNoChange {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// testDinner inserts a chat with a dinner today.
+func testDinner(t *testing.T, db *sqlx.DB) (chat.Repo, Repo, *Dinner) {
+	t.Helper()
+	ctx := context.Background()
+	chatRepo, _ := chat.NewRepo(db)
+	dinnerRepo, _ := NewRepo(db)
+
+	chatId := -time.Now().UnixNano()
+	if _, err := chatRepo.InsertChat(ctx, &chat.Chat{ID: chatId, Type: string(models.ChatTypeGroup)}); err != nil {
+		t.Fatal(err)
+	}
+	d, _, err := dinnerRepo.UpsertDinner(ctx, chatId, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		deleteDinner(db, d.ID)
+		db.Exec(db.Rebind("DELETE FROM chats WHERE id = ?"), chatId)
+	})
+	return chatRepo, dinnerRepo, d
+}
+
+// deleteDinner removes a dinner made by a test, along with its responses.
+func deleteDinner(db *sqlx.DB, id int64) {
+	db.Exec(db.Rebind("DELETE FROM dinners WHERE id = ?"), id)
+}
+
+// testBot returns a bot that talks to a fake Telegram API, which accepts every request. The returned
+// function gives the text of the last message sent or edited.
+func testBot(t *testing.T, chatId int64) (*bot.Bot, func() string) {
+	t.Helper()
+	var mu sync.Mutex
+	var lastText string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/answerCallbackQuery"):
+			fmt.Fprint(w, `{"ok":true,"result":true}`)
+			return
+		case strings.HasSuffix(r.URL.Path, "/sendPoll"):
+			fmt.Fprintf(w, `{"ok":true,"result":{"message_id":2,"date":0,"chat":{"id":%d,"type":"group"},"poll":{"id":"poll-%d"}}}`, chatId, chatId)
+			return
+		case strings.HasSuffix(r.URL.Path, "/sendMessage"), strings.HasSuffix(r.URL.Path, "/editMessageText"):
+			if err := r.ParseMultipartForm(1 << 20); err == nil {
+				mu.Lock()
+				lastText = r.FormValue("text")
+				mu.Unlock()
+			}
+		}
+		fmt.Fprintf(w, `{"ok":true,"result":{"message_id":1,"date":0,"chat":{"id":%d,"type":"group"}}}`, chatId)
+	}))
+	t.Cleanup(server.Close)
+
+	b, err := bot.New("token", bot.WithSkipGetMe(), bot.WithServerURL(server.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b, func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return lastText
+	}
+}
+
+func Test_ConcurrentResponses(t *testing.T) {
+	db := testDB(t)
+	chatRepo, dinnerRepo, d := testDinner(t, db)
+	b, lastText := testBot(t, d.ChatID)
+	s, _ := NewService(b, dinnerRepo, chatRepo)
+	if err := s.(*service).sendDinnerMessage(context.Background(), b, d); err != nil {
+		t.Fatal(err)
+	}
+
+	// Everyone taps join at the same time, while the first person also brings guests
+	users := 20
+	var wg sync.WaitGroup
+	callback := func(userId int64, data string) {
+		defer wg.Done()
+		s.HandleCallbackQuery(context.Background(), b, &models.Update{
+			CallbackQuery: &models.CallbackQuery{
+				ID:   fmt.Sprintf("%d-%s", userId, data),
+				From: models.User{ID: userId, FirstName: fmt.Sprintf("User %d", userId)},
+				Data: data,
+			},
+		})
+	}
+	for i := 1; i <= users; i++ {
+		wg.Add(1)
+		go callback(int64(i), fmt.Sprintf("joindinner_%d", d.ID))
+	}
+	wg.Add(1)
+	go callback(1, fmt.Sprintf("guestsdinner_%d_2", d.ID))
+	wg.Wait()
+
+	attendees, err := dinnerRepo.GetAttendees(context.Background(), d.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(attendees) != users {
+		t.Fatalf("expected %d responses, got %d", users, len(attendees))
+	}
+	for _, a := range attendees {
+		if a.Status != StatusYes {
+			t.Errorf("expected %s to be %s, got %s", a.Name, StatusYes, a.Status)
+		}
+		if a.UserID == 1 && a.Guests != 2 {
+			t.Errorf("expected %s to bring 2 guests, got %d", a.Name, a.Guests)
+		}
+	}
+
+	// The last edit has to show everyone, whichever response it was for
+	text := lastText()
+	for i := 1; i <= users; i++ {
+		if !strings.Contains(text, fmt.Sprintf("User %d", i)) {
+			t.Errorf("expected the dinner message to show User %d, got %q", i, text)
+		}
+	}
+}
+
+func Test_ConcurrentMessageIds(t *testing.T) {
+	db := testDB(t)
+	_, dinnerRepo, d := testDinner(t, db)
+
+	messages := 50
+	var wg sync.WaitGroup
+	for i := 1; i <= messages; i++ {
+		wg.Add(1)
+		go func(messageId int64) {
+			defer wg.Done()
+			if _, err := dinnerRepo.AppendMessageId(context.Background(), d.ID, messageId); err != nil {
+				t.Error(err)
+			}
+		}(int64(i))
+	}
+	wg.Wait()
+
+	actual, err := dinnerRepo.GetDinnerById(context.Background(), d.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(actual.MessageIds) != messages {
+		t.Errorf("expected %d message ids, got %d", messages, len(actual.MessageIds))
+	}
+}
+
+func Test_ConcurrentUpsertDinner(t *testing.T) {
+	db := testDB(t)
+	_, dinnerRepo, d := testDinner(t, db)
+
+	// /getdinner and the scheduled post racing for tomorrow's dinner should end up with one
+	date := d.Date.AddDate(0, 0, 1)
+	ids := make([]int64, 10)
+	inserted := make([]bool, len(ids))
+	var wg sync.WaitGroup
+	for i := range ids {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			actual, ok, err := dinnerRepo.UpsertDinner(context.Background(), d.ChatID, date)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			ids[i], inserted[i] = actual.ID, ok
+		}(i)
+	}
+	wg.Wait()
+	t.Cleanup(func() { deleteDinner(db, ids[0]) })
+
+	created := 0
+	for i, id := range ids {
+		if id != ids[0] {
+			t.Errorf("expected dinner id %d, got %d", ids[0], id)
+		}
+		if inserted[i] {
+			created++
+		}
+	}
+	if created != 1 {
+		t.Errorf("expected dinner to be created once, got %d", created)
+	}
+}
+
+func Test_PollAnswers(t *testing.T) {
+	db := testDB(t)
+	chatRepo, dinnerRepo, d := testDinner(t, db)
+	b, _ := testBot(t, d.ChatID)
+	s, _ := NewService(b, dinnerRepo, chatRepo)
+	ctx := context.Background()
+
+	c, err := chatRepo.GetChatByID(ctx, d.ChatID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.DinnerMode = chat.DinnerModePoll
+	if err := chatRepo.UpdateChat(ctx, c); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.(*service).sendDinnerMessage(ctx, b, d); err != nil {
+		t.Fatal(err)
+	}
+
+	vote := func(userId int64, options ...int) {
+		s.HandlePollAnswer(ctx, b, &models.Update{
+			PollAnswer: &models.PollAnswer{
+				PollID:    fmt.Sprintf("poll-%d", d.ChatID),
+				User:      &models.User{ID: userId, FirstName: fmt.Sprintf("User %d", userId)},
+				OptionIDs: options,
+			},
+		})
+	}
+	vote(1, 0)
+	vote(2, 1)
+	vote(3, 4)
+	vote(3) // retracted
+
+	attendees, err := dinnerRepo.GetAttendees(ctx, d.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[int64]Status{1: StatusYes, 2: StatusLate}
+	if len(attendees) != len(expected) {
+		t.Fatalf("expected %d responses, got %d", len(expected), len(attendees))
+	}
+	for _, a := range attendees {
+		if a.Status != expected[a.UserID] {
+			t.Errorf("expected %s to be %s, got %s", a.Name, expected[a.UserID], a.Status)
+		}
+	}
+}
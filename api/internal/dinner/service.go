@@ -1,32 +1,51 @@
 package dinner
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
 	"log/slog"
 	"net/http"
-	"slices"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf16"
 
 	"github.com/alvinhuhhh/go-alfred/internal/chat"
+	"github.com/alvinhuhhh/go-alfred/internal/config"
 	"github.com/alvinhuhhh/go-alfred/internal/util"
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
+	"github.com/gorilla/mux"
 	"github.com/lib/pq"
 )
 
+const (
+	JobDinner        = "dinner"
+	JobMaybeReminder = "maybereminder"
+	JobLockDinner    = "lockdinner"
+	JobDMReminder    = "dmreminder"
+	JobNudge         = "nudge"
+)
+
 type CronTrigger struct {
-	ChatId int64 `json:"chat_id"`
+	ChatId int64  `json:"chat_id"`
+	Job    string `json:"job,omitempty"`
 }
 
 type Service interface {
 	HandleDinner(ctx context.Context, b *bot.Bot, update *models.Update)
 	HandleCallbackQuery(ctx context.Context, b *bot.Bot, update *models.Update)
+	HandlePollAnswer(ctx context.Context, b *bot.Bot, update *models.Update)
 	CronTrigger(w http.ResponseWriter, r *http.Request)
+	RunJob(ctx context.Context, chatId int64, job string) error
+	GetDinners(w http.ResponseWriter, r *http.Request)
+	GetDinnerStats(w http.ResponseWriter, r *http.Request)
+	ExportDinners(w http.ResponseWriter, r *http.Request)
 }
 
 type service struct {
@@ -51,287 +70,1684 @@ func (s service) HandleDinner(ctx context.Context, b *bot.Bot, update *models.Up
 	}
 	command := update.Message.Text
 
+	today, err := s.today(ctx, update.Message.Chat.ID)
+	if err != nil {
+		slog.Error(err.Error())
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   "Sorry! Having a bit of trouble, will be back soon!",
+		})
+		return
+	}
+
 	if strings.HasPrefix(command, "/getdinner") {
-		d, err := s.getOrInsertDinner(ctx, b, update)
+		date := today
+		if args := strings.Fields(command); len(args) > 1 {
+			date, err = util.ParseDate(strings.Join(args[1:], " "), today)
+			if err != nil {
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID: update.Message.Chat.ID,
+					Text:   "Sorry, I don't understand that date. Try something like /getdinner sat, /getdinner tomorrow or /getdinner 2026-10-24",
+				})
+				return
+			}
+			if date.Before(today) {
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID: update.Message.Chat.ID,
+					Text:   "Sorry, I can't plan dinners in the past!",
+				})
+				return
+			}
+		}
+		d, err := s.getOrInsertDinner(ctx, b, update, date)
 		if err != nil {
 			slog.Error("error getting dinner")
 			return
 		}
+		if err := s.sendDinnerMessage(ctx, b, d); err != nil {
+			slog.Error(err.Error())
+		}
+		return
+	} else if strings.HasPrefix(command, "/upcomingdinners") {
+		dinners, err := s.repo.GetUpcomingDinners(ctx, update.Message.Chat.ID, today)
+		if err != nil {
+			slog.Error(err.Error())
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: update.Message.Chat.ID,
+				Text:   "Sorry! Having a bit of trouble, will be back soon!",
+			})
+			return
+		}
+		if len(dinners) == 0 {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: update.Message.Chat.ID,
+				Text:   "No dinners planned yet! Plan one with e.g. /getdinner sat",
+			})
+			return
+		}
+		lines := []string{}
+		for _, d := range dinners {
+			line := fmt.Sprintf("%s %s - %d eating", d.Date.Format("Mon"), d.Date.Format("02/01/2006"), d.Headcount())
+			if d.IsClosed() {
+				line += " (closed)"
+			}
+			lines = append(lines, line)
+		}
 		b.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID:      update.Message.Chat.ID,
-			Text:        s.parseDinnerMessage(d),
-			ParseMode:   models.ParseModeHTML,
-			ReplyMarkup: s.getKeyboard(d.ID),
+			ChatID:    update.Message.Chat.ID,
+			Text:      fmt.Sprintf("<b>Upcoming dinners:</b>\n%s", strings.Join(lines, "\n")),
+			ParseMode: models.ParseModeHTML,
 		})
 		return
-	} else if strings.HasPrefix(command, "/enddinner") {
-		d, err := s.getOrInsertDinner(ctx, b, update)
+	} else if strings.HasPrefix(command, "/late") {
+		args := strings.Fields(command)
+		if len(args) < 2 {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: update.Message.Chat.ID,
+				Text:   "Let me know when you'll be home, e.g. /late 19:30",
+			})
+			return
+		}
+		eta, err := time.Parse("15:04", args[1])
+		if err != nil {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: update.Message.Chat.ID,
+				Text:   "Sorry, I don't understand that time. Try something like /late 19:30",
+			})
+			return
+		}
+		d, err := s.getOrInsertDinner(ctx, b, update, today)
 		if err != nil {
 			slog.Error("error getting dinner")
 			return
 		}
-		err = s.repo.DeleteDinner(ctx, d.ID)
+		if d.IsClosed() {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: update.Message.Chat.ID,
+				Text:   "Sorry, dinner is closed! Ask an admin to /reopendinner if you need to make changes.",
+			})
+			return
+		}
+		locked, err := s.isLocked(ctx, d)
+		if err != nil {
+			slog.Error(err.Error())
+			return
+		}
+		if locked {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: update.Message.Chat.ID,
+				Text:   "Sorry, dinner is locked! Ask an admin to /unlockdinner if you need to make changes.",
+			})
+			return
+		}
+		user := update.Message.From
+		if err := s.repo.SetAttendeeETA(ctx, d.ID, user.ID, user.FirstName, eta.Format("15:04")); err != nil {
+			slog.Error(err.Error())
+			return
+		}
+		if err := s.refreshAttendees(ctx, b, d, user); err != nil {
+			slog.Error(err.Error())
+		}
+		return
+	} else if strings.HasPrefix(command, "/dinnerstats") {
+		period := ""
+		if args := strings.Fields(command); len(args) > 1 {
+			period = args[1]
+		}
+		from, label, err := statsPeriod(period, today)
+		if err != nil {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: update.Message.Chat.ID,
+				Text:   "Sorry, I can only show stats for the past week, month or year, e.g. /dinnerstats week",
+			})
+			return
+		}
+		stats, err := s.getStats(ctx, update.Message.Chat.ID, from, today)
 		if err != nil {
-			slog.Error("unable to delete dinner")
+			slog.Error(err.Error())
 			b.SendMessage(ctx, &bot.SendMessageParams{
 				ChatID: update.Message.Chat.ID,
-				Text:   "Sorry, I can't delete tonight's dinner",
+				Text:   "Sorry! Having a bit of trouble, will be back soon!",
 			})
 			return
 		}
 		b.SendMessage(ctx, &bot.SendMessageParams{
-			ChatID: update.Message.Chat.ID,
-			Text:   "No more dinner for tonight!",
+			ChatID:    update.Message.Chat.ID,
+			Text:      formatStats(stats, label),
+			ParseMode: models.ParseModeHTML,
 		})
 		return
-	} else {
-		slog.Error("unknown command")
-		return
-	}
-}
-
-func (s service) HandleCallbackQuery(ctx context.Context, b *bot.Bot, update *models.Update) {
-	// Answer callback query first so that Telegram stops spamming updates
-	b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
-		CallbackQueryID: update.CallbackQuery.ID,
-		ShowAlert:       false,
-	})
-
-	callbackData := update.CallbackQuery.Data
-	name := update.CallbackQuery.From.FirstName
-
-	// Get dinner
-	split := strings.Split(callbackData, "_")
-	dinnerId, err := strconv.Atoi(split[1])
-	if err != nil {
-		slog.Error("unable to parse dinner id from callback query data")
-		return
-	}
-	dinner, err := s.repo.GetDinnerById(ctx, int64(dinnerId))
-	if err != nil {
-		slog.Error("unable to get dinner")
-		return
-	}
-
-	switch split[0] {
-	case "joindinner":
-		if !slices.Contains(dinner.Yes, name) {
-			dinner.Yes = append(dinner.Yes, name)
+	} else if strings.HasPrefix(command, "/exportdinners") {
+		format := ExportCSV
+		if args := strings.Fields(command); len(args) > 1 {
+			format = strings.ToLower(args[1])
 		}
-		if slices.Contains(dinner.No, name) {
-			dinner.No = util.Remove(dinner.No, name)
+		if format != ExportCSV && format != ExportICS {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: update.Message.Chat.ID,
+				Text:   "Sorry, I can only export csv or ics, e.g. /exportdinners ics",
+			})
+			return
 		}
-
-	case "leavedinner":
-		if !slices.Contains(dinner.No, name) {
-			dinner.No = append(dinner.No, name)
+		from, to, _ := exportRange("", "", today)
+		data, err := s.exportDinners(ctx, update.Message.Chat.ID, format, from, to)
+		if err != nil {
+			slog.Error(err.Error())
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: update.Message.Chat.ID,
+				Text:   "Sorry! Having a bit of trouble, will be back soon!",
+			})
+			return
 		}
-		if slices.Contains(dinner.Yes, name) {
-			dinner.Yes = util.Remove(dinner.Yes, name)
+		caption := ""
+		if publicUrl, err := config.GetPublicUrl(); err == nil {
+			token, err := chat.FeedToken(ctx, s.chatRepo, update.Message.Chat.ID)
+			if err != nil {
+				slog.Error(err.Error())
+			} else {
+				caption = fmt.Sprintf("Subscribe from your calendar app: %s/api/dinners/%d/export?format=ics&token=%s", publicUrl, update.Message.Chat.ID, token)
+			}
+		}
+		_, err = b.SendDocument(ctx, &bot.SendDocumentParams{
+			ChatID:   update.Message.Chat.ID,
+			Document: &models.InputFileUpload{Filename: "dinners." + format, Data: bytes.NewReader(data)},
+			Caption:  caption,
+		})
+		if err != nil {
+			slog.Error(err.Error())
 		}
-
-	default:
-		slog.Warn(fmt.Sprintf("unknown callback: %s", split[0]))
-		return
-	}
-
-	// Delete existing message
-	if _, err := b.DeleteMessage(ctx, &bot.DeleteMessageParams{
-		ChatID:    update.CallbackQuery.Message.Message.Chat.ID,
-		MessageID: update.CallbackQuery.Message.Message.ID,
-	}); err != nil {
-		slog.Error(err.Error())
-		return
-	}
-
-	// Send response
-	message, err := b.SendMessage(ctx, &bot.SendMessageParams{
-		ChatID:      update.CallbackQuery.Message.Message.Chat.ID,
-		Text:        s.parseDinnerMessage(dinner),
-		ParseMode:   models.ParseModeHTML,
-		ReplyMarkup: s.getKeyboard(dinner.ID),
-	})
-	if err != nil {
-		slog.Error(err.Error())
-		return
-	}
-
-	// Append message ID
-	dinner.MessageIds = s.appendMessageId(dinner.MessageIds, int64(message.ID))
-
-	// Insert db
-	if err := s.repo.UpdateDinner(ctx, dinner); err != nil {
-		slog.Error(err.Error())
-	}
-}
-
-func (s service) CronTrigger(w http.ResponseWriter, r *http.Request) {
-	// Decode request body
-	decoder := json.NewDecoder(r.Body)
-	var ct CronTrigger
-	err := decoder.Decode(&ct)
-	if err != nil {
-		slog.Error(err.Error())
-		w.WriteHeader(http.StatusBadRequest)
 		return
-	}
-
-	// Check if chat is valid
-	_, err = s.chatRepo.GetChatByID(r.Context(), ct.ChatId)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			slog.Error("chat does not exist")
-			w.WriteHeader(http.StatusBadRequest)
+	} else if strings.HasPrefix(command, "/menu") {
+		menu := strings.TrimSpace(strings.TrimPrefix(command, strings.Fields(command)[0]))
+		if menu == "" {
+			// Only asking, so don't start a dinner
+			text := "Nothing on the menu yet! Set it with e.g. /menu chicken rice"
+			d, err := s.repo.GetDinnerByDateAndChatId(ctx, update.Message.Chat.ID, today)
+			if err != nil && err != sql.ErrNoRows {
+				slog.Error(err.Error())
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID: update.Message.Chat.ID,
+					Text:   "Sorry! Having a bit of trouble, will be back soon!",
+				})
+				return
+			}
+			if err == nil && d.Menu != "" {
+				text = fmt.Sprintf("Tonight's menu: %s", d.Menu)
+			}
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: update.Message.Chat.ID,
+				Text:   text,
+			})
 			return
 		}
-
-		slog.Error(err.Error())
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-
-	// Check if dinner exists
-	d, err := s.repo.GetDinnerByDateAndChatId(r.Context(), ct.ChatId, time.Now())
-	if err != nil {
-		if err != sql.ErrNoRows {
-			// Any other error
+		d, inserted, err := s.repo.UpsertDinner(ctx, update.Message.Chat.ID, today)
+		if err != nil {
 			slog.Error(err.Error())
-			w.WriteHeader(http.StatusInternalServerError)
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: update.Message.Chat.ID,
+				Text:   "Sorry! Having a bit of trouble, will be back soon!",
+			})
 			return
 		}
-
-		// Insert new dinner
-		d = &Dinner{
-			ChatID:     ct.ChatId,
-			Date:       time.Now(),
-			Yes:        []string{},
-			No:         []string{},
-			MessageIds: pq.Int64Array{},
+		if d.IsClosed() {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: update.Message.Chat.ID,
+				Text:   "Sorry, dinner is closed! Ask an admin to /reopendinner if you need to make changes.",
+			})
+			return
 		}
-		id, err := s.repo.InsertDinner(r.Context(), d)
-		if err != nil {
+		if strings.EqualFold(menu, "clear") {
+			menu = ""
+		}
+		if err := s.repo.SetMenu(ctx, d.ID, menu); err != nil {
 			slog.Error(err.Error())
-			w.WriteHeader(http.StatusInternalServerError)
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: update.Message.Chat.ID,
+				Text:   "Sorry! Having a bit of trouble, will be back soon!",
+			})
 			return
 		}
-		slog.Info(fmt.Sprintf("inserted dinner id: %v", id))
-		d.ID = id
-	}
-
-	// Send message
-	msg, err := s.bot.SendMessage(r.Context(), &bot.SendMessageParams{
-		ChatID:      ct.ChatId,
-		Text:        s.parseDinnerMessage(d),
-		ParseMode:   models.ParseModeHTML,
-		ReplyMarkup: s.getKeyboard(d.ID),
-	})
-	if err != nil {
-		slog.Error(err.Error())
-		w.WriteHeader(http.StatusInternalServerError)
-		return
-	}
-
-	// Update dinner with message ID
-	d.MessageIds = s.appendMessageId(d.MessageIds, int64(msg.ID))
-	if err := s.repo.UpdateDinner(r.Context(), d); err != nil {
-		slog.Error(err.Error())
-		w.WriteHeader(http.StatusInternalServerError)
+		d.Menu = menu
+		if err := s.showDinner(ctx, b, d, inserted); err != nil {
+			slog.Error(err.Error())
+		}
 		return
-	}
-	w.WriteHeader(http.StatusOK)
-}
-
-func (s service) verifyChat(ctx context.Context, b *bot.Bot, update *models.Update) (*chat.Chat, error) {
-	c, err := s.chatRepo.GetChatByID(ctx, update.Message.Chat.ID)
-	if err != nil {
-		if err != sql.ErrNoRows {
-			// Handle any other error
+	} else if strings.HasPrefix(command, "/cook") {
+		cookId, cookName, err := s.cookFromMessage(ctx, update.Message)
+		if err != nil {
+			if err == errUnknownCook {
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID: update.Message.Chat.ID,
+					Text:   "Sorry, I don't know who that is yet! Try /cook @username, /cook me, /cook none, or reply to their message with /cook",
+				})
+				return
+			}
 			slog.Error(err.Error())
 			b.SendMessage(ctx, &bot.SendMessageParams{
 				ChatID: update.Message.Chat.ID,
 				Text:   "Sorry! Having a bit of trouble, will be back soon!",
 			})
-			return nil, err
-		}
-
-		// Insert new Chat
-		c = &chat.Chat{
-			ID:   update.Message.Chat.ID,
-			Type: string(update.Message.Chat.Type),
+			return
 		}
-		id, err := s.chatRepo.InsertChat(ctx, c)
+		// Setting the cook starts the dinner if needed, without signing anyone up
+		d, inserted, err := s.repo.UpsertDinner(ctx, update.Message.Chat.ID, today)
 		if err != nil {
 			slog.Error(err.Error())
 			b.SendMessage(ctx, &bot.SendMessageParams{
 				ChatID: update.Message.Chat.ID,
 				Text:   "Sorry! Having a bit of trouble, will be back soon!",
 			})
-			return nil, err
+			return
 		}
-		slog.Info(fmt.Sprintf("inserted chat id: %v", id))
-	}
-	return c, nil
-}
-
-func (s service) getOrInsertDinner(ctx context.Context, b *bot.Bot, update *models.Update) (*Dinner, error) {
-	d, err := s.repo.GetDinnerByDateAndChatId(ctx, update.Message.Chat.ID, time.Now())
-	if err != nil {
-		if err != sql.ErrNoRows {
-			// Any other error
+		if d.IsClosed() {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: update.Message.Chat.ID,
+				Text:   "Sorry, dinner is closed! Ask an admin to /reopendinner if you need to make changes.",
+			})
+			return
+		}
+		if err := s.repo.SetCook(ctx, d.ID, cookId, cookName); err != nil {
 			slog.Error(err.Error())
 			b.SendMessage(ctx, &bot.SendMessageParams{
 				ChatID: update.Message.Chat.ID,
 				Text:   "Sorry! Having a bit of trouble, will be back soon!",
 			})
-			return nil, err
+			return
 		}
-
-		// Insert new Dinner
-		d = &Dinner{
-			ChatID:     update.Message.Chat.ID,
-			Date:       time.Now(),
-			Yes:        []string{update.Message.From.FirstName},
-			No:         []string{},
-			MessageIds: pq.Int64Array{int64(update.Message.ID)},
+		d.CookID, d.CookName = cookId, cookName
+		if err := s.showDinner(ctx, b, d, inserted); err != nil {
+			slog.Error(err.Error())
 		}
-		id, err := s.repo.InsertDinner(ctx, d)
+		return
+	} else if strings.HasPrefix(command, "/dinnermode") {
+		c, err := s.chatRepo.GetChatByID(ctx, update.Message.Chat.ID)
 		if err != nil {
+			slog.Error(err.Error())
+			return
+		}
+		args := strings.Fields(command)
+		if len(args) < 2 {
+			text := "Dinners are answered with buttons. Switch to a Telegram poll with /dinnermode poll"
+			if c.UsesPolls() {
+				text = "Dinners are answered with a Telegram poll. Switch to buttons with /dinnermode buttons"
+			}
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: update.Message.Chat.ID,
+				Text:   text,
+			})
+			return
+		}
+		mode := strings.ToLower(args[1])
+		if mode != chat.DinnerModeButtons && mode != chat.DinnerModePoll {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: update.Message.Chat.ID,
+				Text:   "Sorry, I don't know that mode. Try /dinnermode buttons or /dinnermode poll",
+			})
+			return
+		}
+		c.DinnerMode = mode
+		if err := s.chatRepo.UpdateChat(ctx, c); err != nil {
 			slog.Error(err.Error())
 			b.SendMessage(ctx, &bot.SendMessageParams{
 				ChatID: update.Message.Chat.ID,
 				Text:   "Sorry! Having a bit of trouble, will be back soon!",
 			})
-			return nil, err
+			return
 		}
-		slog.Info(fmt.Sprintf("inserted dinner id: %v", id))
-		d.ID = id
-	}
-	return d, nil
-}
-
-func (s service) getKeyboard(id int64) *models.InlineKeyboardMarkup {
-	return &models.InlineKeyboardMarkup{
-		InlineKeyboard: [][]models.InlineKeyboardButton{
-			{
+		text := "Done! Dinners will be answered with buttons"
+		if c.UsesPolls() {
+			text = "Done! Dinners will be answered with a Telegram poll"
+		}
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   text,
+		})
+		return
+	} else if strings.HasPrefix(command, "/unlockdinner") {
+		// Restricted to admins by chat.RequirePermission
+		d, err := s.repo.GetDinnerByDateAndChatId(ctx, update.Message.Chat.ID, today)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID: update.Message.Chat.ID,
+					Text:   "There's no dinner tonight!",
+				})
+				return
+			}
+			slog.Error(err.Error())
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: update.Message.Chat.ID,
+				Text:   "Sorry! Having a bit of trouble, will be back soon!",
+			})
+			return
+		}
+		if err := s.repo.UnlockDinner(ctx, d.ID); err != nil {
+			slog.Error(err.Error())
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: update.Message.Chat.ID,
+				Text:   "Sorry! Having a bit of trouble, will be back soon!",
+			})
+			return
+		}
+		d.LockedAt = nil
+		d.Reopened = true
+		if err := s.refreshDinnerMessages(ctx, b, d); err != nil {
+			slog.Error(err.Error())
+		}
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   "Dinner is open again for changes!",
+		})
+		return
+	} else if strings.HasPrefix(command, "/enddinner") {
+		// Restricted to admins by chat.RequirePermission
+		d, err := s.repo.GetDinnerByDateAndChatId(ctx, update.Message.Chat.ID, today)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID: update.Message.Chat.ID,
+					Text:   "There's no dinner tonight!",
+				})
+				return
+			}
+			slog.Error(err.Error())
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: update.Message.Chat.ID,
+				Text:   "Sorry! Having a bit of trouble, will be back soon!",
+			})
+			return
+		}
+		if d.IsClosed() {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: update.Message.Chat.ID,
+				Text:   "Dinner is already closed! Use /reopendinner to open it again.",
+			})
+			return
+		}
+		if err := s.repo.CloseDinner(ctx, d.ID); err != nil {
+			slog.Error("unable to close dinner")
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: update.Message.Chat.ID,
+				Text:   "Sorry, I can't end tonight's dinner",
+			})
+			return
+		}
+		now := time.Now()
+		d.ClosedAt = &now
+		if err := s.refreshDinnerMessages(ctx, b, d); err != nil {
+			slog.Error(err.Error())
+		}
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   "No more dinner for tonight!",
+		})
+		return
+	} else if strings.HasPrefix(command, "/reopendinner") {
+		// Restricted to admins by chat.RequirePermission
+		d, err := s.repo.GetDinnerByDateAndChatId(ctx, update.Message.Chat.ID, today)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID: update.Message.Chat.ID,
+					Text:   "There's no dinner tonight! Start one with /getdinner",
+				})
+				return
+			}
+			slog.Error(err.Error())
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: update.Message.Chat.ID,
+				Text:   "Sorry! Having a bit of trouble, will be back soon!",
+			})
+			return
+		}
+		if !d.IsClosed() {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: update.Message.Chat.ID,
+				Text:   "Dinner is still open!",
+			})
+			return
+		}
+		if err := s.repo.ReopenDinner(ctx, d.ID); err != nil {
+			slog.Error(err.Error())
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: update.Message.Chat.ID,
+				Text:   "Sorry! Having a bit of trouble, will be back soon!",
+			})
+			return
+		}
+		d.ClosedAt = nil
+		if err := s.refreshDinnerMessages(ctx, b, d); err != nil {
+			slog.Error(err.Error())
+		}
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   "Dinner is back on!",
+		})
+		return
+	} else {
+		slog.Error("unknown command")
+		return
+	}
+}
+
+func (s service) HandleCallbackQuery(ctx context.Context, b *bot.Bot, update *models.Update) {
+	// Always answer callback query so that Telegram stops spamming updates
+	answered := false
+	defer func() {
+		if !answered {
+			b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+				CallbackQueryID: update.CallbackQuery.ID,
+				ShowAlert:       false,
+			})
+		}
+	}()
+
+	callbackData := update.CallbackQuery.Data
+	user := update.CallbackQuery.From
+
+	// Get dinner
+	split := strings.Split(callbackData, "_")
+	dinnerId, err := strconv.Atoi(split[1])
+	if err != nil {
+		slog.Error("unable to parse dinner id from callback query data")
+		return
+	}
+	dinner, err := s.repo.GetDinnerById(ctx, int64(dinnerId))
+	if err != nil {
+		slog.Error("unable to get dinner")
+		return
+	}
+
+	// Refuse changes once dinner is closed or the poll is locked
+	if split[0] != "repostdinner" {
+		if dinner.IsClosed() {
+			answered = true
+			b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+				CallbackQueryID: update.CallbackQuery.ID,
+				Text:            "Sorry, dinner is closed! Ask an admin to /reopendinner if you need to make changes.",
+				ShowAlert:       true,
+			})
+			if err := s.refreshDinnerMessages(ctx, b, dinner); err != nil {
+				slog.Error(err.Error())
+			}
+			return
+		}
+		locked, err := s.isLocked(ctx, dinner)
+		if err != nil {
+			slog.Error(err.Error())
+			return
+		}
+		// The cutoff is for the headcount, so cooks can still be sorted out afterwards
+		if locked && split[0] != "cookdinner" {
+			answered = true
+			b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+				CallbackQueryID: update.CallbackQuery.ID,
+				Text:            "Sorry, dinner is locked! Ask an admin to /unlockdinner if you need to make changes.",
+				ShowAlert:       true,
+			})
+			if err := s.refreshDinnerMessages(ctx, b, dinner); err != nil {
+				slog.Error(err.Error())
+			}
+			return
+		}
+	}
+	answered = true
+	b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: update.CallbackQuery.ID,
+		ShowAlert:       false,
+	})
+
+	// Each response is a single atomic update, so that people tapping at the same time don't
+	// overwrite each other, and guests and ETA carry over from the stored response
+	switch split[0] {
+	case "joindinner":
+		err = s.repo.SetAttendeeStatus(ctx, dinner.ID, user.ID, user.FirstName, StatusYes)
+
+	case "leavedinner":
+		err = s.repo.SetAttendeeStatus(ctx, dinner.ID, user.ID, user.FirstName, StatusNo)
+
+	case "maybedinner":
+		err = s.repo.SetAttendeeStatus(ctx, dinner.ID, user.ID, user.FirstName, StatusMaybe)
+
+	case "latedinner":
+		err = s.repo.SetAttendeeStatus(ctx, dinner.ID, user.ID, user.FirstName, StatusLate)
+
+	case "takeawaydinner":
+		err = s.repo.SetAttendeeStatus(ctx, dinner.ID, user.ID, user.FirstName, StatusTakeaway)
+
+	case "guestsdinner":
+		if len(split) < 3 {
+			slog.Error("missing guest count in callback query data")
+			return
+		}
+		guests, parseErr := strconv.Atoi(split[2])
+		if parseErr != nil || guests < 0 {
+			slog.Error("unable to parse guest count from callback query data")
+			return
+		}
+		err = s.repo.SetAttendeeGuests(ctx, dinner.ID, user.ID, user.FirstName, guests)
+
+	case "repostdinner":
+		if err := s.repostDinnerMessage(ctx, b, dinner); err != nil {
+			slog.Error(err.Error())
+		}
+		return
+
+	case "cookdinner":
+		// Pressing it again backs out of cooking
+		cookId, cookName := &user.ID, user.FirstName
+		if dinner.CookID != nil && *dinner.CookID == user.ID {
+			cookId, cookName = nil, ""
+		}
+		if err := s.repo.SetCook(ctx, dinner.ID, cookId, cookName); err != nil {
+			slog.Error(err.Error())
+			return
+		}
+		dinner.CookID, dinner.CookName = cookId, cookName
+		if err := s.refreshDinnerMessages(ctx, b, dinner); err != nil {
+			slog.Error(err.Error())
+		}
+		return
+
+	default:
+		slog.Warn(fmt.Sprintf("unknown callback: %s", split[0]))
+		return
+	}
+
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+	if err := s.refreshAttendees(ctx, b, dinner, &user); err != nil {
+		slog.Error(err.Error())
+	}
+
+	// Answering a reminder DM also updates the reminder
+	if msg := update.CallbackQuery.Message.Message; msg != nil && msg.Chat.Type == models.ChatTypePrivate {
+		if err := s.refreshReminder(ctx, b, dinner, msg, user.ID); err != nil {
+			slog.Error(err.Error())
+		}
+	}
+}
+
+// HandlePollAnswer records a vote on a dinner's native poll as the voter's response. Retracting the
+// vote removes the response.
+func (s service) HandlePollAnswer(ctx context.Context, b *bot.Bot, update *models.Update) {
+	answer := update.PollAnswer
+	if answer.User == nil {
+		// Votes cast on behalf of a chat can't be matched to anyone
+		return
+	}
+	p, err := s.repo.GetPoll(ctx, answer.PollID)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			slog.Error(err.Error())
+		}
+		return
+	}
+	dinner, err := s.repo.GetDinnerById(ctx, p.DinnerID)
+	if err != nil {
+		slog.Error("unable to get dinner")
+		return
+	}
+
+	// Polls are stopped when dinner is locked or closed, so only votes cast just before get here
+	locked, err := s.isLocked(ctx, dinner)
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+	if dinner.IsClosed() || locked {
+		slog.Warn(fmt.Sprintf("ignoring vote on dinner id %d from user id %d, dinner no longer accepts changes", dinner.ID, answer.User.ID))
+		if err := s.refreshDinnerMessages(ctx, b, dinner); err != nil {
+			slog.Error(err.Error())
+		}
+		return
+	}
+
+	user := answer.User
+	if len(answer.OptionIDs) == 0 {
+		err = s.repo.DeleteAttendee(ctx, dinner.ID, user.ID)
+	} else if option := answer.OptionIDs[0]; option >= 0 && option < len(PollOptions) {
+		err = s.repo.SetAttendeeStatus(ctx, dinner.ID, user.ID, user.FirstName, PollOptions[option])
+	} else {
+		err = fmt.Errorf("unknown dinner poll option %d", option)
+	}
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+	if err := s.refreshAttendees(ctx, b, dinner, user); err != nil {
+		slog.Error(err.Error())
+	}
+}
+
+func (s service) CronTrigger(w http.ResponseWriter, r *http.Request) {
+	// Decode request body
+	decoder := json.NewDecoder(r.Body)
+	var ct CronTrigger
+	err := decoder.Decode(&ct)
+	if err != nil {
+		slog.Error(err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	if err := s.RunJob(r.Context(), ct.ChatId, ct.Job); err != nil {
+		if err == sql.ErrNoRows {
+			slog.Error("chat does not exist")
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		slog.Error(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// RunJob runs a scheduled job for a chat. It returns sql.ErrNoRows if the chat does not exist.
+func (s service) RunJob(ctx context.Context, chatId int64, job string) error {
+	// Check if chat is valid
+	c, err := s.chatRepo.GetChatByID(ctx, chatId)
+	if err != nil {
+		return err
+	}
+
+	switch job {
+	case JobMaybeReminder:
+		return s.remindMaybes(ctx, s.bot, chatId)
+
+	case JobLockDinner:
+		return s.lockDinner(ctx, s.bot, chatId)
+
+	case JobDMReminder:
+		return s.remindNonResponders(ctx, s.bot, chatId)
+
+	case JobNudge:
+		return s.nudgeDinner(ctx, s.bot, c)
+
+	case JobDinner, "":
+		// Triggers without a job come from schedules made before there were other jobs
+		return s.postDinner(ctx, s.bot, c)
+
+	default:
+		return fmt.Errorf("unknown job: %s", job)
+	}
+}
+
+// postDinner posts today's dinner, then mentions whoever hasn't answered yet if the chat wants nudges.
+func (s service) postDinner(ctx context.Context, b *bot.Bot, c *chat.Chat) error {
+	today := util.DateIn(time.Now(), c.Location())
+	d, inserted, err := s.repo.UpsertDinner(ctx, c.ID, today)
+	if err != nil {
+		return err
+	}
+	if inserted {
+		slog.Info(fmt.Sprintf("inserted dinner id: %v", d.ID))
+	}
+	if d.IsClosed() {
+		slog.Info(fmt.Sprintf("dinner id %d is closed, not posting", d.ID))
+		return nil
+	}
+
+	if err := s.sendDinnerMessage(ctx, b, d); err != nil {
+		return err
+	}
+	return s.nudgeAbsent(ctx, b, c, d)
+}
+
+func (s service) GetDinners(w http.ResponseWriter, r *http.Request) {
+	c := r.URL.Query().Get("chatId")
+	chatId, err := strconv.ParseInt(c, 10, 64)
+	if err != nil {
+		slog.Error(err.Error())
+		slog.Error("unable to parse chatId from request")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	dinners, err := s.repo.GetDinnersForChatId(r.Context(), chatId, 30, 0)
+	if err != nil {
+		slog.Error(err.Error())
+		slog.Error("error fetching dinners")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	type dinnerResponse struct {
+		Dinner
+		Headcount int `json:"headcount"`
+	}
+	res := []dinnerResponse{}
+	for _, d := range dinners {
+		res = append(res, dinnerResponse{Dinner: d, Headcount: d.Headcount()})
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(res)
+}
+
+func (s service) GetDinnerStats(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	chatId, err := strconv.ParseInt(vars["chatId"], 10, 64)
+	if err != nil {
+		slog.Error(err.Error())
+		slog.Error("unable to parse chatId from request")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	today, err := s.today(r.Context(), chatId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		slog.Error(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	from, _, err := statsPeriod(r.URL.Query().Get("period"), today)
+	if err != nil {
+		slog.Error(err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	stats, err := s.getStats(r.Context(), chatId, from, today)
+	if err != nil {
+		slog.Error(err.Error())
+		slog.Error("error fetching dinner stats")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(stats)
+}
+
+func (s service) ExportDinners(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	chatId, err := strconv.ParseInt(vars["chatId"], 10, 64)
+	if err != nil {
+		slog.Error(err.Error())
+		slog.Error("unable to parse chatId from request")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = ExportCSV
+	}
+	if format != ExportCSV && format != ExportICS {
+		slog.Error(fmt.Sprintf("unknown export format: %s", format))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	today, err := s.today(r.Context(), chatId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		slog.Error(err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	from, to, err := exportRange(r.URL.Query().Get("from"), r.URL.Query().Get("to"), today)
+	if err != nil {
+		slog.Error(err.Error())
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	data, err := s.exportDinners(r.Context(), chatId, format, from, to)
+	if err != nil {
+		slog.Error(err.Error())
+		slog.Error("error exporting dinners")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", exportContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="dinners.%s"`, format))
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
+
+// exportDinners renders the chat's dinners between from and to in the given export format.
+func (s service) exportDinners(ctx context.Context, chatId int64, format string, from, to time.Time) ([]byte, error) {
+	dinners, err := s.repo.GetDinnersBetween(ctx, chatId, from, to)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if format == ExportICS {
+		err = writeICS(&buf, dinners, time.Now())
+	} else {
+		err = writeCSV(&buf, dinners)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (s service) verifyChat(ctx context.Context, b *bot.Bot, update *models.Update) (*chat.Chat, error) {
+	c, err := s.chatRepo.GetChatByID(ctx, update.Message.Chat.ID)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			// Handle any other error
+			slog.Error(err.Error())
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: update.Message.Chat.ID,
+				Text:   "Sorry! Having a bit of trouble, will be back soon!",
+			})
+			return nil, err
+		}
+
+		// Insert new Chat
+		c = &chat.Chat{
+			ID:   update.Message.Chat.ID,
+			Type: string(update.Message.Chat.Type),
+		}
+		id, err := s.chatRepo.InsertChat(ctx, c)
+		if err != nil {
+			slog.Error(err.Error())
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: update.Message.Chat.ID,
+				Text:   "Sorry! Having a bit of trouble, will be back soon!",
+			})
+			return nil, err
+		}
+		slog.Info(fmt.Sprintf("inserted chat id: %v", id))
+	}
+	return c, nil
+}
+
+// getOrInsertDinner returns the dinner on date, creating it with the caller attending if there is none.
+// Closed dinners are returned as they are, so that ending a dinner does not start a new one.
+func (s service) getOrInsertDinner(ctx context.Context, b *bot.Bot, update *models.Update, date time.Time) (*Dinner, error) {
+	user := update.Message.From
+	d, inserted, err := s.repo.UpsertDinner(ctx, update.Message.Chat.ID, date)
+	if err != nil {
+		slog.Error(err.Error())
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: update.Message.Chat.ID,
+			Text:   "Sorry! Having a bit of trouble, will be back soon!",
+		})
+		return nil, err
+	}
+	if inserted {
+		slog.Info(fmt.Sprintf("inserted dinner id: %v", d.ID))
+
+		// Whoever asks for dinner is coming
+		if err := s.repo.SetAttendeeStatus(ctx, d.ID, user.ID, user.FirstName, StatusYes); err != nil {
+			slog.Error(err.Error())
+		}
+		d.Attendees, err = s.repo.GetAttendees(ctx, d.ID)
+		if err != nil {
+			slog.Error(err.Error())
+		}
+	} else if a := d.Attendee(user.ID); a != nil && a.Name != user.FirstName {
+		// Refresh display name in case the user has renamed themselves
+		if err := s.repo.UpdateAttendeeName(ctx, d.ID, user.ID, user.FirstName); err != nil {
+			slog.Error(err.Error())
+		}
+		a.Name = user.FirstName
+	}
+	return d, nil
+}
+
+// getKeyboard returns the poll buttons of a dinner, or nil once it is closed. Chats answering with
+// native polls only get the buttons that the poll can't replace.
+func (s service) getKeyboard(d *Dinner, usesPolls bool) models.ReplyMarkup {
+	if d.IsClosed() {
+		return nil
+	}
+	id := d.ID
+	if usesPolls {
+		// Answers come from the poll, but it has no way to bring guests
+		return &models.InlineKeyboardMarkup{
+			InlineKeyboard: [][]models.InlineKeyboardButton{
+				{
+					{Text: "+1", CallbackData: fmt.Sprintf("guestsdinner_%d_1", id)},
+					{Text: "+2", CallbackData: fmt.Sprintf("guestsdinner_%d_2", id)},
+					{Text: "No guests", CallbackData: fmt.Sprintf("guestsdinner_%d_0", id)},
+				},
+				{
+					{Text: "I'll cook", CallbackData: fmt.Sprintf("cookdinner_%d", id)},
+					{Text: "Repost to bottom", CallbackData: fmt.Sprintf("repostdinner_%d", id)},
+				},
+			},
+		}
+	}
+	return &models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{
+			{
 				{Text: "Join Dinner", CallbackData: fmt.Sprintf("joindinner_%d", id)},
 				{Text: "Leave Dinner", CallbackData: fmt.Sprintf("leavedinner_%d", id)},
 			},
+			{
+				{Text: "Maybe", CallbackData: fmt.Sprintf("maybedinner_%d", id)},
+				{Text: "Late", CallbackData: fmt.Sprintf("latedinner_%d", id)},
+				{Text: "Takeaway please", CallbackData: fmt.Sprintf("takeawaydinner_%d", id)},
+			},
+			{
+				{Text: "+1", CallbackData: fmt.Sprintf("guestsdinner_%d_1", id)},
+				{Text: "+2", CallbackData: fmt.Sprintf("guestsdinner_%d_2", id)},
+				{Text: "No guests", CallbackData: fmt.Sprintf("guestsdinner_%d_0", id)},
+			},
+			{
+				{Text: "I'll cook", CallbackData: fmt.Sprintf("cookdinner_%d", id)},
+				{Text: "Repost to bottom", CallbackData: fmt.Sprintf("repostdinner_%d", id)},
+			},
 		},
 	}
 }
 
-func (s service) parseDinnerMessage(d *Dinner) string {
+// renderDinnerMessage builds the poll message of a dinner as it stands.
+func (s service) renderDinnerMessage(ctx context.Context, d *Dinner) (string, error) {
+	today, err := s.today(ctx, d.ChatID)
+	if err != nil {
+		return "", err
+	}
+	members, err := s.chatRepo.GetMembers(ctx, d.ChatID)
+	if err != nil {
+		return "", err
+	}
+	diets := map[int64][]string{}
+	for _, m := range members {
+		diets[m.UserID] = m.Diet
+	}
+	return s.parseDinnerMessage(d, today, diets), nil
+}
+
+func (s service) parseDinnerMessage(d *Dinner, today time.Time, diets map[int64][]string) string {
 	date := d.Date
-	yes := strings.Join(d.Yes, "\n")
-	no := strings.Join(d.No, "\n")
-	return fmt.Sprintf("\n<b>Dinner tonight:</b>\nDate: %s\n\n<u>YES:</u>\n%s\n\n<u>NO:</u>\n%s\n\n", date.Format("02/01/2006"), yes, no)
+
+	sections := map[Status][]string{}
+	for _, a := range d.Attendees {
+		sections[a.Status] = append(sections[a.Status], attendeeLine(a))
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("\n<b>%s:</b>\nDate: %s\nHeadcount: %d\n", dinnerHeading(date, today), date.Format("02/01/2006"), d.Headcount()))
+	if d.CookName != "" {
+		sb.WriteString(fmt.Sprintf("Cook: %s\n", html.EscapeString(d.CookName)))
+	}
+	if d.Menu != "" {
+		sb.WriteString(fmt.Sprintf("Menu: %s\n", html.EscapeString(d.Menu)))
+	}
+	if d.IsClosed() {
+		sb.WriteString("<b>Closed</b> - final list\n")
+	} else if d.LockedAt != nil {
+		sb.WriteString("<b>Locked</b> - no more changes please!\n")
+	}
+	sb.WriteString("\n")
+	sb.WriteString(fmt.Sprintf("<u>YES:</u>\n%s\n", strings.Join(sections[StatusYes], "\n")))
+	if summary := dietSummary(d, diets); summary != "" {
+		sb.WriteString(fmt.Sprintf("<i>Dietary: %s</i>\n", html.EscapeString(summary)))
+	}
+	sb.WriteString("\n")
+	if len(sections[StatusLate]) > 0 {
+		sb.WriteString(fmt.Sprintf("<u>LATE:</u>\n%s\n\n", strings.Join(sections[StatusLate], "\n")))
+	}
+	if len(sections[StatusTakeaway]) > 0 {
+		sb.WriteString(fmt.Sprintf("<u>TAKEAWAY:</u>\n%s\n\n", strings.Join(sections[StatusTakeaway], "\n")))
+	}
+	if len(sections[StatusMaybe]) > 0 {
+		sb.WriteString(fmt.Sprintf("<u>MAYBE:</u>\n%s\n\n", strings.Join(sections[StatusMaybe], "\n")))
+	}
+	sb.WriteString(fmt.Sprintf("<u>NO:</u>\n%s\n\n", strings.Join(sections[StatusNo], "\n")))
+	return sb.String()
+}
+
+// dietSummary lists the dietary requirements of everyone eating, e.g. "vegetarian (Alice, Bob), no-peanuts (Carol)".
+func dietSummary(d *Dinner, diets map[int64][]string) string {
+	tags := []string{}
+	names := map[string][]string{}
+	for _, a := range d.Attendees {
+		if !a.Status.IsEating() {
+			continue
+		}
+		for _, tag := range diets[a.UserID] {
+			if _, ok := names[tag]; !ok {
+				tags = append(tags, tag)
+			}
+			names[tag] = append(names[tag], a.Name)
+		}
+	}
+	parts := []string{}
+	for _, tag := range tags {
+		parts = append(parts, fmt.Sprintf("%s (%s)", tag, strings.Join(names[tag], ", ")))
+	}
+	return strings.Join(parts, ", ")
+}
+
+var errUnknownCook = errors.New("unknown cook")
+
+// cookFromMessage works out who a /cook command assigns: someone mentioned by name or @username,
+// the author of the message being replied to, or otherwise the sender. It returns a nil id for
+// /cook none, and errUnknownCook if the mentioned user has not been seen in the chat.
+func (s service) cookFromMessage(ctx context.Context, msg *models.Message) (*int64, string, error) {
+	for _, e := range msg.Entities {
+		if e.Type == models.MessageEntityTypeTextMention && e.User != nil {
+			return &e.User.ID, e.User.FirstName, nil
+		}
+	}
+
+	args := strings.Fields(msg.Text)
+	if len(args) > 1 {
+		switch arg := strings.ToLower(args[1]); {
+		case arg == "none" || arg == "off":
+			return nil, "", nil
+		case arg == "me":
+			return &msg.From.ID, msg.From.FirstName, nil
+		case strings.HasPrefix(arg, "@"):
+			m, err := s.chatRepo.GetMemberByUsername(ctx, msg.Chat.ID, strings.TrimPrefix(arg, "@"))
+			if err != nil {
+				if err == sql.ErrNoRows {
+					return nil, "", errUnknownCook
+				}
+				return nil, "", err
+			}
+			return &m.UserID, m.FirstName, nil
+		default:
+			return nil, "", errUnknownCook
+		}
+	}
+
+	if reply := msg.ReplyToMessage; reply != nil && reply.From != nil && !reply.From.IsBot {
+		return &reply.From.ID, reply.From.FirstName, nil
+	}
+	return &msg.From.ID, msg.From.FirstName, nil
+}
+
+// attendeeLine describes a response in HTML, e.g. "Alice (ETA 19:30) (+1)".
+func attendeeLine(a Attendee) string {
+	return html.EscapeString(a.Name) + attendeeDetails(a)
+}
+
+// attendeeDetails describes the ETA and guests of a response, e.g. " (ETA 19:30) (+1)".
+func attendeeDetails(a Attendee) string {
+	line := ""
+	if a.Status == StatusLate && a.ETA != "" {
+		line += fmt.Sprintf(" (ETA %s)", a.ETA)
+	}
+	if a.Status.IsEating() && a.Guests > 0 {
+		line += fmt.Sprintf(" (+%d)", a.Guests)
+	}
+	return line
+}
+
+// dinnerHeading describes when a dinner is relative to today, e.g. "Dinner tonight" or "Dinner on Saturday".
+func dinnerHeading(date, today time.Time) string {
+	y1, m1, d1 := date.Date()
+	y2, m2, d2 := today.Date()
+	days := int(time.Date(y1, m1, d1, 0, 0, 0, 0, time.UTC).Sub(time.Date(y2, m2, d2, 0, 0, 0, 0, time.UTC)).Hours() / 24)
+	switch {
+	case days == 0:
+		return "Dinner tonight"
+	case days == 1:
+		return "Dinner tomorrow"
+	case days > 1 && days < 7:
+		return fmt.Sprintf("Dinner on %s", date.Weekday())
+	default:
+		return fmt.Sprintf("Dinner on %s %s", date.Weekday(), date.Format("2 Jan"))
+	}
+}
+
+// refreshAttendees refreshes the poll messages of a dinner after user has responded.
+func (s service) refreshAttendees(ctx context.Context, b *bot.Bot, d *Dinner, user *models.User) error {
+	// Refresh display name in case the user has renamed themselves
+	if err := s.repo.UpdateAttendeeName(ctx, d.ID, user.ID, user.FirstName); err != nil {
+		slog.Error(err.Error())
+	}
+	return s.refreshDinnerMessages(ctx, b, d)
+}
+
+// remindMaybes nudges everyone still on "Maybe" for tonight's dinner to make up their mind.
+func (s service) remindMaybes(ctx context.Context, b *bot.Bot, chatId int64) error {
+	today, err := s.today(ctx, chatId)
+	if err != nil {
+		return err
+	}
+	d, err := s.repo.GetDinnerByDateAndChatId(ctx, chatId, today)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+	if d.IsClosed() {
+		return nil
+	}
+
+	mentions := []string{}
+	for _, a := range d.Attendees {
+		if a.Status != StatusMaybe {
+			continue
+		}
+		if a.UserID > 0 {
+			mentions = append(mentions, userMention(a.UserID, a.Name))
+		} else {
+			mentions = append(mentions, html.EscapeString(a.Name))
+		}
+	}
+	if len(mentions) == 0 {
+		return nil
+	}
+
+	c, err := s.chatRepo.GetChatByID(ctx, chatId)
+	if err != nil {
+		return err
+	}
+	text := fmt.Sprintf("%s, are you coming for dinner tonight? Please let us know soon!", strings.Join(mentions, ", "))
+	if c.DinnerCutoff != "" {
+		text = fmt.Sprintf("%s, are you coming for dinner tonight? Please let us know before %s!", strings.Join(mentions, ", "), c.DinnerCutoff)
+	}
+
+	_, err = b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatId,
+		Text:      text,
+		ParseMode: models.ParseModeHTML,
+	})
+	return err
+}
+
+// remindNonResponders privately messages the chat's members who haven't responded to tonight's dinner,
+// with buttons to respond. Members who haven't started a private chat with the bot are asked to in the
+// group, once until they do.
+func (s service) remindNonResponders(ctx context.Context, b *bot.Bot, chatId int64) error {
+	c, err := s.chatRepo.GetChatByID(ctx, chatId)
+	if err != nil {
+		return err
+	}
+	today := util.DateIn(time.Now(), c.Location())
+	d, err := s.repo.GetDinnerByDateAndChatId(ctx, chatId, today)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+	if d.IsClosed() {
+		return nil
+	}
+	locked, err := s.isLocked(ctx, d)
+	if err != nil || locked {
+		return err
+	}
+	members, err := s.chatRepo.GetMembers(ctx, chatId)
+	if err != nil {
+		return err
+	}
+
+	unreachable := []string{}
+	for _, m := range members {
+		if !m.IsMember() || m.DMBlockedAt != nil || d.Attendee(m.UserID) != nil {
+			continue
+		}
+		_, err := b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:      m.UserID,
+			Text:        reminderText(d, today, c.DinnerCutoff, nil),
+			ParseMode:   models.ParseModeHTML,
+			ReplyMarkup: s.getReminderKeyboard(d),
+		})
+		if errors.Is(err, bot.ErrorForbidden) {
+			// The member hasn't started a private chat with the bot, or has blocked it
+			slog.Info(fmt.Sprintf("unable to remind user id %d privately: %s", m.UserID, err.Error()))
+			newly, err := s.chatRepo.MarkDMBlocked(ctx, chatId, m.UserID)
+			if err != nil {
+				slog.Error(err.Error())
+				continue
+			}
+			if newly {
+				unreachable = append(unreachable, userMention(m.UserID, m.FirstName))
+			}
+			continue
+		}
+		if err != nil {
+			slog.Error(err.Error())
+		}
+	}
+	if len(unreachable) == 0 {
+		return nil
+	}
+
+	_, err = b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:    chatId,
+		Text:      fmt.Sprintf("%s, I couldn't message you about dinner. Send me /start in a private chat to get reminders!", strings.Join(unreachable, ", ")),
+		ParseMode: models.ParseModeHTML,
+	})
+	return err
+}
+
+// nudgeDinner nudges the members who haven't responded to tonight's dinner, if it is time to again.
+func (s service) nudgeDinner(ctx context.Context, b *bot.Bot, c *chat.Chat) error {
+	if c.NudgeInterval <= 0 {
+		return nil
+	}
+	today := util.DateIn(time.Now(), c.Location())
+	d, err := s.repo.GetDinnerByDateAndChatId(ctx, c.ID, today)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+	return s.nudgeAbsent(ctx, b, c, d)
+}
+
+// nudgeAbsent mentions the chat's members who haven't responded to a dinner, at most once every nudge
+// interval. Nudges stop at the cutoff and are held back during quiet hours.
+func (s service) nudgeAbsent(ctx context.Context, b *bot.Bot, c *chat.Chat, d *Dinner) error {
+	if c.NudgeInterval <= 0 || d.IsClosed() || c.InQuietHours(time.Now()) {
+		return nil
+	}
+	locked, err := s.isLocked(ctx, d)
+	if err != nil || locked {
+		return err
+	}
+	members, err := s.chatRepo.GetMembers(ctx, c.ID)
+	if err != nil {
+		return err
+	}
+	absent := []chat.Member{}
+	for _, m := range members {
+		// Members only known from the Mini App have no name to mention them by
+		if m.IsMember() && m.FirstName != "" && d.Attendee(m.UserID) == nil {
+			absent = append(absent, m)
+		}
+	}
+	if len(absent) == 0 {
+		return nil
+	}
+
+	nudge, err := s.repo.ClaimNudge(ctx, d.ID, time.Duration(c.NudgeInterval)*time.Minute)
+	if err != nil || !nudge {
+		return err
+	}
+	today := util.DateIn(time.Now(), c.Location())
+	text, entities := nudgeText(dinnerHeading(d.Date, today), absent)
+	_, err = b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:   c.ID,
+		Text:     text,
+		Entities: entities,
+	})
+	return err
+}
+
+// nudgeText builds a nudge that mentions each member with a text_mention entity, so that members
+// without a username are notified too. Entity offsets are counted in UTF-16 code units.
+func nudgeText(heading string, members []chat.Member) (string, []models.MessageEntity) {
+	var sb strings.Builder
+	entities := []models.MessageEntity{}
+	sb.WriteString(heading + "! Still waiting to hear from ")
+	for i, m := range members {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		entities = append(entities, models.MessageEntity{
+			Type:   models.MessageEntityTypeTextMention,
+			Offset: utf16Len(sb.String()),
+			Length: utf16Len(m.FirstName),
+			User:   &models.User{ID: m.UserID, FirstName: m.FirstName},
+		})
+		sb.WriteString(m.FirstName)
+	}
+	sb.WriteString(" - please answer above!")
+	return sb.String(), entities
+}
+
+func utf16Len(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
+
+// refreshReminder updates a reminder DM to show its recipient's response.
+func (s service) refreshReminder(ctx context.Context, b *bot.Bot, d *Dinner, msg *models.Message, userId int64) error {
+	c, err := s.chatRepo.GetChatByID(ctx, d.ChatID)
+	if err != nil {
+		return err
+	}
+	_, err = b.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:      msg.Chat.ID,
+		MessageID:   msg.ID,
+		Text:        reminderText(d, util.DateIn(time.Now(), c.Location()), c.DinnerCutoff, d.Attendee(userId)),
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: s.getReminderKeyboard(d),
+	})
+	if isMessageNotModified(err) {
+		return nil
+	}
+	return err
+}
+
+// reminderText builds a reminder DM, showing the recipient's response once they have one.
+func reminderText(d *Dinner, today time.Time, cutoff string, a *Attendee) string {
+	heading := fmt.Sprintf("<b>%s (%s)</b>", dinnerHeading(d.Date, today), d.Date.Format("02/01/2006"))
+	if a != nil {
+		return fmt.Sprintf("%s\nThanks! You're down as %s: %s\nYou can still change your answer below.", heading, a.Status, attendeeLine(*a))
+	}
+	text := fmt.Sprintf("%s\nYou haven't said if you're coming yet!", heading)
+	if cutoff != "" {
+		text += fmt.Sprintf(" Please let everyone know before %s.", cutoff)
+	}
+	return text
+}
+
+// getReminderKeyboard returns the buttons of a reminder DM.
+func (s service) getReminderKeyboard(d *Dinner) models.ReplyMarkup {
+	id := d.ID
+	return &models.InlineKeyboardMarkup{
+		InlineKeyboard: [][]models.InlineKeyboardButton{
+			{
+				{Text: "Join Dinner", CallbackData: fmt.Sprintf("joindinner_%d", id)},
+				{Text: "Leave Dinner", CallbackData: fmt.Sprintf("leavedinner_%d", id)},
+			},
+			{
+				{Text: "Maybe", CallbackData: fmt.Sprintf("maybedinner_%d", id)},
+				{Text: "Late", CallbackData: fmt.Sprintf("latedinner_%d", id)},
+				{Text: "Takeaway please", CallbackData: fmt.Sprintf("takeawaydinner_%d", id)},
+			},
+		},
+	}
+}
+
+// userMention links to a user by id, so that they are notified even without a username.
+func userMention(userId int64, name string) string {
+	return fmt.Sprintf(`<a href="tg://user?id=%d">%s</a>`, userId, html.EscapeString(name))
+}
+
+// lockDinner locks tonight's dinner if the chat's cutoff has passed, and shows it on the poll messages.
+func (s service) lockDinner(ctx context.Context, b *bot.Bot, chatId int64) error {
+	today, err := s.today(ctx, chatId)
+	if err != nil {
+		return err
+	}
+	d, err := s.repo.GetDinnerByDateAndChatId(ctx, chatId, today)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return err
+	}
+	if d.IsClosed() {
+		return nil
+	}
+	locked, err := s.isLocked(ctx, d)
+	if err != nil || !locked {
+		return err
+	}
+	return s.refreshDinnerMessages(ctx, b, d)
+}
+
+// isLocked reports whether a dinner no longer accepts changes. Dinners are locked lazily the first
+// time they are checked after the chat's cutoff time, unless an admin has reopened them.
+func (s service) isLocked(ctx context.Context, d *Dinner) (bool, error) {
+	if d.LockedAt != nil {
+		return true, nil
+	}
+	if d.Reopened {
+		return false, nil
+	}
+
+	c, err := s.chatRepo.GetChatByID(ctx, d.ChatID)
+	if err != nil {
+		return false, err
+	}
+	if c.DinnerCutoff == "" {
+		return false, nil
+	}
+	cutoff, err := util.TimeOnDate(d.Date, c.DinnerCutoff, c.Location())
+	if err != nil {
+		return false, err
+	}
+	if time.Now().Before(cutoff) {
+		return false, nil
+	}
+
+	if err := s.repo.LockDinner(ctx, d.ID); err != nil {
+		return false, err
+	}
+	now := time.Now()
+	d.LockedAt = &now
+	return true, nil
+}
+
+// today returns tonight's dinner date in the chat's timezone.
+func (s service) today(ctx context.Context, chatId int64) (time.Time, error) {
+	c, err := s.chatRepo.GetChatByID(ctx, chatId)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return util.DateIn(time.Now(), c.Location()), nil
+}
+
+// sendDinnerMessage posts a new poll message and records it alongside the existing live ones.
+// In poll mode, a native poll is posted below it unless the dinner already has one.
+func (s service) sendDinnerMessage(ctx context.Context, b *bot.Bot, d *Dinner) error {
+	c, err := s.chatRepo.GetChatByID(ctx, d.ChatID)
+	if err != nil {
+		return err
+	}
+	text, err := s.renderDinnerMessage(ctx, d)
+	if err != nil {
+		return err
+	}
+	msg, err := b.SendMessage(ctx, &bot.SendMessageParams{
+		ChatID:      d.ChatID,
+		Text:        text,
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: s.getKeyboard(d, c.UsesPolls()),
+	})
+	if err != nil {
+		return err
+	}
+	d.MessageIds, err = s.repo.AppendMessageId(ctx, d.ID, int64(msg.ID))
+	if err != nil {
+		return err
+	}
+	if c.UsesPolls() {
+		return s.sendDinnerPoll(ctx, b, d)
+	}
+	return nil
 }
 
-func (s service) appendMessageId(arr pq.Int64Array, id int64) pq.Int64Array {
-	// Cap array length at 1000 elements
-	arr = append(arr, id)
-	if len(arr) > 1000 {
-		arr = arr[1:]
+// sendDinnerPoll posts a native poll for the dinner, unless it already has a live one or no longer
+// accepts answers.
+func (s service) sendDinnerPoll(ctx context.Context, b *bot.Bot, d *Dinner) error {
+	if d.IsClosed() || d.LockedAt != nil {
+		return nil
 	}
-	return arr
+	polls, err := s.repo.GetPolls(ctx, d.ID)
+	if err != nil || len(polls) > 0 {
+		return err
+	}
+	today, err := s.today(ctx, d.ChatID)
+	if err != nil {
+		return err
+	}
+
+	options := []models.InputPollOption{}
+	for _, status := range PollOptions {
+		options = append(options, models.InputPollOption{Text: pollOptionText(status)})
+	}
+	isAnonymous := false
+	msg, err := b.SendPoll(ctx, &bot.SendPollParams{
+		ChatID:      d.ChatID,
+		Question:    fmt.Sprintf("%s (%s)?", dinnerHeading(d.Date, today), d.Date.Format("02/01/2006")),
+		Options:     options,
+		IsAnonymous: &isAnonymous,
+	})
+	if err != nil {
+		return err
+	}
+	if msg.Poll == nil {
+		return fmt.Errorf("sent dinner poll message id %d without a poll", msg.ID)
+	}
+	return s.repo.InsertPoll(ctx, &Poll{ID: msg.Poll.ID, DinnerID: d.ID, MessageID: int64(msg.ID)})
+}
+
+// stopDinnerPolls closes the live native polls of a dinner once it no longer accepts answers.
+// A new poll is posted if the dinner is reopened.
+func (s service) stopDinnerPolls(ctx context.Context, b *bot.Bot, d *Dinner) error {
+	polls, err := s.repo.GetPolls(ctx, d.ID)
+	if err != nil || len(polls) == 0 {
+		return err
+	}
+	for _, p := range polls {
+		if _, err := b.StopPoll(ctx, &bot.StopPollParams{
+			ChatID:    d.ChatID,
+			MessageID: int(p.MessageID),
+		}); err != nil {
+			slog.Warn(fmt.Sprintf("unable to stop dinner poll id %s: %s", p.ID, err.Error()))
+		}
+	}
+	return s.repo.DeletePolls(ctx, d.ID)
+}
+
+// pollOptionText returns the native poll option for a status.
+func pollOptionText(status Status) string {
+	switch status {
+	case StatusYes:
+		return "Coming"
+	case StatusLate:
+		return "Coming late"
+	case StatusTakeaway:
+		return "Takeaway please"
+	case StatusMaybe:
+		return "Maybe"
+	default:
+		return "Not coming"
+	}
+}
+
+// repostDinnerMessage replaces all live poll messages, and the native poll in poll mode, with new ones at
+// the bottom of the chat.
+func (s service) repostDinnerMessage(ctx context.Context, b *bot.Bot, d *Dinner) error {
+	unlock, err := s.repo.LockMessages(ctx, d.ID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	latest, err := s.repo.GetDinnerById(ctx, d.ID)
+	if err != nil {
+		return err
+	}
+	*d = *latest
+
+	for _, id := range d.MessageIds {
+		if _, err := b.DeleteMessage(ctx, &bot.DeleteMessageParams{
+			ChatID:    d.ChatID,
+			MessageID: int(id),
+		}); err != nil {
+			slog.Warn(fmt.Sprintf("unable to delete dinner message id %d: %s", id, err.Error()))
+		}
+	}
+	if d.MessageIds, err = s.repo.RemoveMessageIds(ctx, d.ID, d.MessageIds); err != nil {
+		return err
+	}
+
+	// Move the native poll too, stopping it if it can't be deleted so that it isn't answered any more
+	polls, err := s.repo.GetPolls(ctx, d.ID)
+	if err != nil {
+		return err
+	}
+	for _, p := range polls {
+		if _, err := b.DeleteMessage(ctx, &bot.DeleteMessageParams{
+			ChatID:    d.ChatID,
+			MessageID: int(p.MessageID),
+		}); err != nil {
+			slog.Warn(fmt.Sprintf("unable to delete dinner poll id %s: %s", p.ID, err.Error()))
+			if _, err := b.StopPoll(ctx, &bot.StopPollParams{
+				ChatID:    d.ChatID,
+				MessageID: int(p.MessageID),
+			}); err != nil {
+				slog.Warn(fmt.Sprintf("unable to stop dinner poll id %s: %s", p.ID, err.Error()))
+			}
+		}
+	}
+	if len(polls) > 0 {
+		if err := s.repo.DeletePolls(ctx, d.ID); err != nil {
+			return err
+		}
+	}
+	return s.sendDinnerMessage(ctx, b, d)
+}
+
+// showDinner posts the poll message of a dinner that was just created, or refreshes its live ones.
+func (s service) showDinner(ctx context.Context, b *bot.Bot, d *Dinner, inserted bool) error {
+	if inserted {
+		return s.sendDinnerMessage(ctx, b, d)
+	}
+	return s.refreshDinnerMessages(ctx, b, d)
+}
+
+// refreshDinnerMessages edits every live poll message in place. Messages that can no longer be
+// edited are dropped, and a new one is sent if none are left. Native polls are stopped once the
+// dinner is locked or closed, and posted again if it is reopened.
+func (s service) refreshDinnerMessages(ctx context.Context, b *bot.Bot, d *Dinner) error {
+	// Responses that arrive together each refresh the messages. Taking turns and reading the dinner
+	// afresh makes sure the last edit shows every response, not just the ones its caller had seen.
+	unlock, err := s.repo.LockMessages(ctx, d.ID)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	latest, err := s.repo.GetDinnerById(ctx, d.ID)
+	if err != nil {
+		return err
+	}
+	*d = *latest
+
+	c, err := s.chatRepo.GetChatByID(ctx, d.ChatID)
+	if err != nil {
+		return err
+	}
+	text, err := s.renderDinnerMessage(ctx, d)
+	if err != nil {
+		return err
+	}
+	dropped := pq.Int64Array{}
+	for _, id := range d.MessageIds {
+		_, err := b.EditMessageText(ctx, &bot.EditMessageTextParams{
+			ChatID:      d.ChatID,
+			MessageID:   int(id),
+			Text:        text,
+			ParseMode:   models.ParseModeHTML,
+			ReplyMarkup: s.getKeyboard(d, c.UsesPolls()),
+		})
+		switch {
+		case err == nil, isMessageNotModified(err):
+		case isMessageGone(err):
+			slog.Warn(fmt.Sprintf("dropping dinner message id %d: %s", id, err.Error()))
+			dropped = append(dropped, id)
+		default:
+			slog.Error(err.Error())
+		}
+	}
+	if len(dropped) > 0 {
+		if d.MessageIds, err = s.repo.RemoveMessageIds(ctx, d.ID, dropped); err != nil {
+			return err
+		}
+		if len(d.MessageIds) == 0 {
+			return s.sendDinnerMessage(ctx, b, d)
+		}
+	}
+
+	if d.IsClosed() || d.LockedAt != nil {
+		return s.stopDinnerPolls(ctx, b, d)
+	}
+	if c.UsesPolls() {
+		return s.sendDinnerPoll(ctx, b, d)
+	}
+	return nil
+}
+
+func isMessageNotModified(err error) bool {
+	return errors.Is(err, bot.ErrorBadRequest) && strings.Contains(err.Error(), "message is not modified")
+}
+
+// isMessageGone reports whether a message was deleted or is too old to edit. Other bad requests, such as
+// text that fails to parse, say nothing about the message itself.
+func isMessageGone(err error) bool {
+	return errors.Is(err, bot.ErrorBadRequest) &&
+		(strings.Contains(err.Error(), "message to edit not found") || strings.Contains(err.Error(), "message can't be edited"))
 }
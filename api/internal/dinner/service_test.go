@@ -0,0 +1,68 @@
+package dinner
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alvinhuhhh/go-alfred/internal/chat"
+)
+
+func Test_DietSummary(t *testing.T) {
+	d := &Dinner{Attendees: []Attendee{
+		{UserID: 1, Name: "Alice", Status: StatusYes},
+		{UserID: 2, Name: "Bob", Status: StatusLate},
+		{UserID: 3, Name: "Carol", Status: StatusNo},
+	}}
+	diets := map[int64][]string{
+		1: {"vegetarian"},
+		2: {"vegetarian", "no-peanuts"},
+		3: {"halal"},
+	}
+	expected := "vegetarian (Alice, Bob), no-peanuts (Bob)"
+	if actual := dietSummary(d, diets); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
+
+func Test_ReminderText(t *testing.T) {
+	today := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+	d := &Dinner{Date: today}
+
+	expected := "<b>Dinner tonight (19/10/2026)</b>\nYou haven't said if you're coming yet! Please let everyone know before 17:30."
+	if actual := reminderText(d, today, "17:30", nil); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+
+	a := &Attendee{Name: "Alice", Status: StatusLate, ETA: "19:30"}
+	expected = "<b>Dinner tonight (19/10/2026)</b>\nThanks! You're down as LATE: Alice (ETA 19:30)\nYou can still change your answer below."
+	if actual := reminderText(d, today, "17:30", a); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
+
+func Test_NudgeText(t *testing.T) {
+	members := []chat.Member{
+		{UserID: 1, FirstName: "Zoë"},
+		{UserID: 2, FirstName: "Bob"},
+	}
+	text, entities := nudgeText("Dinner tonight", members)
+
+	expected := "Dinner tonight! Still waiting to hear from Zoë, Bob - please answer above!"
+	if text != expected {
+		t.Errorf("expected %q, got %q", expected, text)
+	}
+	if len(entities) != 2 {
+		t.Fatalf("expected 2 mentions, got %d", len(entities))
+	}
+	if e := entities[1]; e.Offset != 48 || e.Length != 3 || e.User.ID != 2 {
+		t.Errorf("expected mention of user id 2 at 48 for 3, got user id %d at %d for %d", e.User.ID, e.Offset, e.Length)
+	}
+}
+
+func Test_AttendeeLine(t *testing.T) {
+	a := Attendee{Name: "Tom & <Jerry>", Status: StatusYes, Guests: 1}
+	expected := "Tom &amp; &lt;Jerry&gt; (+1)"
+	if actual := attendeeLine(a); actual != expected {
+		t.Errorf("expected %q, got %q", expected, actual)
+	}
+}
@@ -0,0 +1,88 @@
+package dinner
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"slices"
+	"strings"
+	"time"
+)
+
+// statsPeriod returns the start of a stats period ending today, and how to describe it.
+// The period is one of week, month or year, and defaults to month.
+func statsPeriod(period string, today time.Time) (time.Time, string, error) {
+	switch strings.ToLower(period) {
+	case "week":
+		return today.AddDate(0, 0, -6), "week", nil
+	case "", "month":
+		return today.AddDate(0, -1, 1), "month", nil
+	case "year":
+		return today.AddDate(-1, 0, 1), "year", nil
+	default:
+		return time.Time{}, "", fmt.Errorf("unknown stats period: %s", period)
+	}
+}
+
+// getStats aggregates the chat's dinners between from and to.
+func (s service) getStats(ctx context.Context, chatId int64, from, to time.Time) (*Stats, error) {
+	weekdays, err := s.repo.GetWeekdayStats(ctx, chatId, from, to)
+	if err != nil {
+		return nil, err
+	}
+	people, err := s.repo.GetAttendanceStats(ctx, chatId, from, to)
+	if err != nil {
+		return nil, err
+	}
+	cooks, err := s.repo.GetCookStats(ctx, chatId, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &Stats{From: from, To: to, People: people, Cooks: cooks, Weekdays: weekdays}
+	for _, w := range weekdays {
+		stats.Dinners += w.Dinners
+	}
+	for i := range stats.People {
+		if stats.Dinners > 0 {
+			stats.People[i].AttendanceRate = float64(stats.People[i].Attended) / float64(stats.Dinners)
+		}
+	}
+	return stats, nil
+}
+
+func formatStats(stats *Stats, label string) string {
+	if stats.Dinners == 0 {
+		return fmt.Sprintf("No dinners in the past %s yet!", label)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("<b>Dinner stats for the past %s:</b>\n%d dinners\n\n", label, stats.Dinners))
+
+	sb.WriteString("<u>Attendance:</u>\n")
+	for _, p := range stats.People {
+		sb.WriteString(fmt.Sprintf("%s - %d/%d (%.0f%%)", html.EscapeString(p.Name), p.Attended, stats.Dinners, p.AttendanceRate*100))
+		if p.LongestStreak > 1 {
+			sb.WriteString(fmt.Sprintf(", streak %d (best %d)", p.CurrentStreak, p.LongestStreak))
+		}
+		sb.WriteString("\n")
+	}
+
+	if len(stats.Cooks) > 0 {
+		sb.WriteString("\n<u>Cooking:</u>\n")
+		for _, c := range stats.Cooks {
+			sb.WriteString(fmt.Sprintf("%s - %d/%d (%.0f%%)\n", html.EscapeString(c.Name), c.Cooked, stats.Dinners, float64(c.Cooked)/float64(stats.Dinners)*100))
+		}
+	}
+
+	// Busiest days first, by average headcount
+	weekdays := slices.Clone(stats.Weekdays)
+	slices.SortStableFunc(weekdays, func(a, b WeekdayStats) int {
+		return b.Headcount*a.Dinners - a.Headcount*b.Dinners
+	})
+	sb.WriteString("\n<u>Busiest days:</u>\n")
+	for _, w := range weekdays {
+		sb.WriteString(fmt.Sprintf("%s - %.1f eating on average over %d dinners\n", w.Weekday, float64(w.Headcount)/float64(w.Dinners), w.Dinners))
+	}
+	return sb.String()
+}
@@ -0,0 +1,31 @@
+package dinner
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_StatsPeriod(t *testing.T) {
+	today := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)
+	cases := map[string]string{
+		"week":  "2026-10-12",
+		"":      "2026-09-19",
+		"Month": "2026-09-19",
+		"year":  "2025-10-19",
+	}
+	for period, expected := range cases {
+		from, _, err := statsPeriod(period, today)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual := from.Format("2006-01-02"); actual != expected {
+			t.Errorf("statsPeriod(%q): expected %s, got %s", period, expected, actual)
+		}
+	}
+}
+
+func Test_StatsPeriodUnknown(t *testing.T) {
+	if _, _, err := statsPeriod("fortnight", time.Now()); err == nil {
+		t.Error("expected error for unknown period")
+	}
+}
@@ -0,0 +1,14 @@
+package grocery
+
+import "time"
+
+type Item struct {
+	ID          int64      `db:"id" json:"id"`
+	ChatID      int64      `db:"chat_id" json:"chatId"`
+	Name        string     `db:"name" json:"name"`
+	DinnerDate  *time.Time `db:"dinner_date" json:"dinnerDate"`
+	AddedBy     int64      `db:"added_by" json:"addedBy"`
+	AddedByName string     `db:"added_by_name" json:"addedByName"`
+	Done        bool       `db:"done" json:"done"`
+	CreatedAt   time.Time  `db:"created_at" json:"createdAt"`
+}
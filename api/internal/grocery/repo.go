@@ -0,0 +1,86 @@
+package grocery
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+type Repo interface {
+	GetItemById(ctx context.Context, id int64) (*Item, error)
+	GetItemsForChatId(ctx context.Context, chatId int64) ([]Item, error)
+	InsertItem(ctx context.Context, item *Item) (int64, error)
+	UpdateItem(ctx context.Context, item *Item) error
+	DeleteItem(ctx context.Context, id int64) error
+	DeleteDoneItems(ctx context.Context, chatId int64) error
+}
+
+type repo struct {
+	db *sqlx.DB
+}
+
+func NewRepo(db *sqlx.DB) (Repo, error) {
+	return &repo{db: db}, nil
+}
+
+func (r repo) GetItemById(ctx context.Context, id int64) (*Item, error) {
+	query := "SELECT id, chat_id, name, dinner_date, added_by, added_by_name, done, created_at FROM groceries WHERE id = ?"
+	query = r.db.Rebind(query)
+	var i Item
+	if err := r.db.GetContext(ctx, &i, query, id); err != nil {
+		return nil, err
+	}
+	return &i, nil
+}
+
+// GetItemsForChatId returns the chat's list, soonest dinner first and then in the order items were added.
+func (r repo) GetItemsForChatId(ctx context.Context, chatId int64) ([]Item, error) {
+	query := "SELECT id, chat_id, name, dinner_date, added_by, added_by_name, done, created_at FROM groceries WHERE chat_id = ? ORDER BY dinner_date ASC NULLS LAST, id ASC"
+	query = r.db.Rebind(query)
+	i := []Item{}
+	if err := r.db.SelectContext(ctx, &i, query, chatId); err != nil {
+		return nil, err
+	}
+	return i, nil
+}
+
+func (r repo) InsertItem(ctx context.Context, item *Item) (int64, error) {
+	query := "INSERT INTO groceries(chat_id, name, dinner_date, added_by, added_by_name) VALUES (?,?,?,?,?) RETURNING id"
+	query = r.db.Rebind(query)
+	var id int64
+	err := r.db.QueryRowContext(ctx, query, item.ChatID, item.Name, dateOrNil(item.DinnerDate), item.AddedBy, item.AddedByName).Scan(&id)
+	if err != nil {
+		return -1, err
+	}
+	return id, nil
+}
+
+func (r repo) UpdateItem(ctx context.Context, item *Item) error {
+	query := "UPDATE groceries SET name = ?, dinner_date = ?, done = ? WHERE id = ?"
+	query = r.db.Rebind(query)
+	_, err := r.db.ExecContext(ctx, query, item.Name, dateOrNil(item.DinnerDate), item.Done, item.ID)
+	return err
+}
+
+func (r repo) DeleteItem(ctx context.Context, id int64) error {
+	query := "DELETE FROM groceries WHERE id = ?"
+	query = r.db.Rebind(query)
+	_, err := r.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// DeleteDoneItems clears everything that has been ticked off the chat's list.
+func (r repo) DeleteDoneItems(ctx context.Context, chatId int64) error {
+	query := "DELETE FROM groceries WHERE chat_id = ? AND done"
+	query = r.db.Rebind(query)
+	_, err := r.db.ExecContext(ctx, query, chatId)
+	return err
+}
+
+func dateOrNil(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return t.Format("2006-01-02")
+}
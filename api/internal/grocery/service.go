@@ -0,0 +1,392 @@
+package grocery
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alvinhuhhh/go-alfred/internal/chat"
+	"github.com/alvinhuhhh/go-alfred/internal/middleware"
+	"github.com/alvinhuhhh/go-alfred/internal/util"
+	"github.com/go-telegram/bot"
+	"github.com/go-telegram/bot/models"
+	"github.com/gorilla/mux"
+)
+
+type Service interface {
+	HandleGroceries(ctx context.Context, b *bot.Bot, update *models.Update)
+	HandleCallbackQuery(ctx context.Context, b *bot.Bot, update *models.Update)
+	GetItems(w http.ResponseWriter, r *http.Request)
+	InsertItem(w http.ResponseWriter, r *http.Request)
+	UpdateItem(w http.ResponseWriter, r *http.Request)
+	DeleteItem(w http.ResponseWriter, r *http.Request)
+}
+
+type service struct {
+	bot        *bot.Bot
+	repo       Repo
+	chatRepo   chat.Repo
+	membership middleware.ChatMembership
+}
+
+func NewService(b *bot.Bot, r Repo, cr chat.Repo, m middleware.ChatMembership) (Service, error) {
+	return &service{
+		bot:        b,
+		repo:       r,
+		chatRepo:   cr,
+		membership: m,
+	}, nil
+}
+
+func (s service) HandleGroceries(ctx context.Context, b *bot.Bot, update *models.Update) {
+	chatId := update.Message.Chat.ID
+	args := strings.Fields(update.Message.Text)
+	command, _, _ := strings.Cut(args[0], "@")
+
+	c, err := s.chatRepo.GetChatByID(ctx, chatId)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "Please /start me first!",
+			})
+			return
+		}
+		slog.Error(err.Error())
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatId,
+			Text:   "Sorry! Having a bit of trouble, will be back soon!",
+		})
+		return
+	}
+
+	switch command {
+	case "/buy":
+		today := util.DateIn(time.Now(), c.Location())
+		names, date := parseItems(strings.Join(args[1:], " "), today)
+		if len(names) == 0 {
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "What should I add? Try something like /buy milk, eggs or /buy chicken for sat",
+			})
+			return
+		}
+		for _, name := range names {
+			item := Item{
+				ChatID:      chatId,
+				Name:        name,
+				DinnerDate:  date,
+				AddedBy:     update.Message.From.ID,
+				AddedByName: update.Message.From.FirstName,
+			}
+			if _, err := s.repo.InsertItem(ctx, &item); err != nil {
+				slog.Error(err.Error())
+				b.SendMessage(ctx, &bot.SendMessageParams{
+					ChatID: chatId,
+					Text:   "Sorry! Having a bit of trouble, will be back soon!",
+				})
+				return
+			}
+		}
+		text := fmt.Sprintf("Added %s to the /groceries list!", strings.Join(names, ", "))
+		if date != nil {
+			text = fmt.Sprintf("Added %s to the /groceries list for dinner on %s!", strings.Join(names, ", "), date.Format("Mon 02/01"))
+		}
+		b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID: chatId,
+			Text:   text,
+		})
+
+	case "/groceries":
+		items, err := s.repo.GetItemsForChatId(ctx, chatId)
+		if err != nil {
+			slog.Error(err.Error())
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "Sorry! Having a bit of trouble, will be back soon!",
+			})
+			return
+		}
+		_, err = b.SendMessage(ctx, &bot.SendMessageParams{
+			ChatID:      chatId,
+			Text:        parseChecklist(items),
+			ParseMode:   models.ParseModeHTML,
+			ReplyMarkup: getKeyboard(chatId, items),
+		})
+		if err != nil {
+			slog.Error(err.Error())
+			b.SendMessage(ctx, &bot.SendMessageParams{
+				ChatID: chatId,
+				Text:   "Sorry! Having a bit of trouble, will be back soon!",
+			})
+		}
+	}
+}
+
+func (s service) HandleCallbackQuery(ctx context.Context, b *bot.Bot, update *models.Update) {
+	// Always answer callback query so that Telegram stops spamming updates
+	defer b.AnswerCallbackQuery(ctx, &bot.AnswerCallbackQueryParams{
+		CallbackQueryID: update.CallbackQuery.ID,
+		ShowAlert:       false,
+	})
+
+	msg := update.CallbackQuery.Message.Message
+	if msg == nil {
+		slog.Warn("grocery checklist message is no longer accessible")
+		return
+	}
+	chatId := msg.Chat.ID
+
+	split := strings.Split(update.CallbackQuery.Data, "_")
+	if len(split) < 2 {
+		slog.Error("missing id in callback query data")
+		return
+	}
+	id, err := strconv.ParseInt(split[1], 10, 64)
+	if err != nil {
+		slog.Error("unable to parse id from callback query data")
+		return
+	}
+
+	switch split[0] {
+	case "groceryitem":
+		item, err := s.repo.GetItemById(ctx, id)
+		if err != nil && err != sql.ErrNoRows {
+			slog.Error(err.Error())
+			return
+		}
+		// Items can be removed from the Mini App while the checklist is still up
+		if item != nil && item.ChatID == chatId {
+			item.Done = !item.Done
+			if err := s.repo.UpdateItem(ctx, item); err != nil {
+				slog.Error(err.Error())
+				return
+			}
+		}
+
+	case "groceryclear":
+		if id != chatId {
+			slog.Warn(fmt.Sprintf("grocery checklist for chat id %d posted in chat id %d", id, chatId))
+			return
+		}
+		if err := s.repo.DeleteDoneItems(ctx, chatId); err != nil {
+			slog.Error(err.Error())
+			return
+		}
+
+	default:
+		slog.Warn(fmt.Sprintf("unknown callback: %s", split[0]))
+		return
+	}
+
+	items, err := s.repo.GetItemsForChatId(ctx, chatId)
+	if err != nil {
+		slog.Error(err.Error())
+		return
+	}
+	_, err = b.EditMessageText(ctx, &bot.EditMessageTextParams{
+		ChatID:      chatId,
+		MessageID:   msg.ID,
+		Text:        parseChecklist(items),
+		ParseMode:   models.ParseModeHTML,
+		ReplyMarkup: getKeyboard(chatId, items),
+	})
+	if err != nil {
+		slog.Error(err.Error())
+	}
+}
+
+func (s service) GetItems(w http.ResponseWriter, r *http.Request) {
+	c := r.URL.Query().Get("chatId")
+	chatId, err := strconv.ParseInt(c, 10, 64)
+	if err != nil {
+		slog.Error(err.Error())
+		slog.Error("unable to parse chatId from request")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	items, err := s.repo.GetItemsForChatId(r.Context(), chatId)
+	if err != nil {
+		slog.Error(err.Error())
+		slog.Error("error fetching groceries")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(items)
+}
+
+func (s service) InsertItem(w http.ResponseWriter, r *http.Request) {
+	decoder := json.NewDecoder(r.Body)
+	var item Item
+	if err := decoder.Decode(&item); err != nil {
+		slog.Error(err.Error())
+		slog.Error("error parsing request body")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	item.Name = strings.TrimSpace(item.Name)
+	if item.Name == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !middleware.CheckChatMember(w, r, s.membership, item.ChatID) {
+		return
+	}
+	if data, ok := middleware.InitDataFromContext(r.Context()); ok {
+		item.AddedBy = data.User.ID
+		item.AddedByName = data.User.FirstName
+	}
+	id, err := s.repo.InsertItem(r.Context(), &item)
+	if err != nil {
+		slog.Error(err.Error())
+		slog.Error("unable to insert grocery item")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	item.ID = id
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(item)
+}
+
+func (s service) UpdateItem(w http.ResponseWriter, r *http.Request) {
+	item, ok := s.getItem(w, r)
+	if !ok {
+		return
+	}
+	decoder := json.NewDecoder(r.Body)
+	var update Item
+	if err := decoder.Decode(&update); err != nil {
+		slog.Error(err.Error())
+		slog.Error("error parsing request body")
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	update.Name = strings.TrimSpace(update.Name)
+	if update.Name == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	item.Name = update.Name
+	item.DinnerDate = update.DinnerDate
+	item.Done = update.Done
+	if err := s.repo.UpdateItem(r.Context(), item); err != nil {
+		slog.Error(err.Error())
+		slog.Error("unable to update grocery item")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(item)
+}
+
+func (s service) DeleteItem(w http.ResponseWriter, r *http.Request) {
+	item, ok := s.getItem(w, r)
+	if !ok {
+		return
+	}
+	if err := s.repo.DeleteItem(r.Context(), item.ID); err != nil {
+		slog.Error(err.Error())
+		slog.Error("error deleting grocery item")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// getItem loads the item in the request path and checks that the caller belongs to its chat,
+// writing an error response if not.
+func (s service) getItem(w http.ResponseWriter, r *http.Request) (*Item, bool) {
+	vars := mux.Vars(r)
+	id, err := strconv.ParseInt(vars["id"], 10, 64)
+	if err != nil {
+		slog.Error(err.Error())
+		slog.Error("unable to parse id from request")
+		w.WriteHeader(http.StatusBadRequest)
+		return nil, false
+	}
+	item, err := s.repo.GetItemById(r.Context(), id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			w.WriteHeader(http.StatusNotFound)
+			return nil, false
+		}
+		slog.Error(err.Error())
+		slog.Error("error fetching grocery item")
+		w.WriteHeader(http.StatusInternalServerError)
+		return nil, false
+	}
+	if !middleware.CheckChatMember(w, r, s.membership, item.ChatID) {
+		return nil, false
+	}
+	return item, true
+}
+
+// parseItems splits "milk, eggs for sat" into item names and the optional dinner date they are for.
+func parseItems(text string, today time.Time) ([]string, *time.Time) {
+	var date *time.Time
+	if i := strings.LastIndex(strings.ToLower(text), " for "); i >= 0 {
+		if d, err := util.ParseDate(text[i+len(" for "):], today); err == nil && !d.Before(today) {
+			text = text[:i]
+			date = &d
+		}
+	}
+
+	names := []string{}
+	for _, name := range strings.Split(text, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, date
+}
+
+// maxChecklistItems keeps the checklist well under the 100 buttons Telegram allows in a keyboard.
+const maxChecklistItems = 50
+
+func parseChecklist(items []Item) string {
+	if len(items) == 0 {
+		return "<b>Groceries:</b>\nNothing to buy! Add something with e.g. /buy milk, eggs"
+	}
+	remaining := 0
+	for _, i := range items {
+		if !i.Done {
+			remaining++
+		}
+	}
+	text := fmt.Sprintf("<b>Groceries:</b>\n%d of %d left to buy. Tap an item to tick it off.", remaining, len(items))
+	if len(items) > maxChecklistItems {
+		text += fmt.Sprintf("\nShowing the first %d, the rest are in the Mini App.", maxChecklistItems)
+	}
+	return text
+}
+
+func getKeyboard(chatId int64, items []Item) *models.InlineKeyboardMarkup {
+	rows := [][]models.InlineKeyboardButton{}
+	for _, i := range items[:min(len(items), maxChecklistItems)] {
+		label := i.Name
+		if i.DinnerDate != nil {
+			label = fmt.Sprintf("%s (%s)", i.Name, i.DinnerDate.Format("Mon 02/01"))
+		}
+		if i.Done {
+			label = "✅ " + label
+		} else {
+			label = "⬜ " + label
+		}
+		rows = append(rows, []models.InlineKeyboardButton{
+			{Text: label, CallbackData: fmt.Sprintf("groceryitem_%d", i.ID)},
+		})
+	}
+	if len(items) > 0 {
+		rows = append(rows, []models.InlineKeyboardButton{
+			{Text: "Clear ticked items", CallbackData: fmt.Sprintf("groceryclear_%d", chatId)},
+		})
+	}
+	return &models.InlineKeyboardMarkup{InlineKeyboard: rows}
+}
@@ -0,0 +1,46 @@
+package grocery
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func Test_ParseItems(t *testing.T) {
+	today := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC) // Sunday
+
+	names, date := parseItems("milk, eggs ,, bread", today)
+	if !slices.Equal(names, []string{"milk", "eggs", "bread"}) {
+		t.Errorf("unexpected names: %v", names)
+	}
+	if date != nil {
+		t.Errorf("expected no date, got %v", date)
+	}
+
+	names, date = parseItems("chicken, rice for sat", today)
+	if !slices.Equal(names, []string{"chicken", "rice"}) {
+		t.Errorf("unexpected names: %v", names)
+	}
+	if date == nil || date.Format("2006-01-02") != "2026-10-24" {
+		t.Errorf("expected 2026-10-24, got %v", date)
+	}
+}
+
+func Test_ParseItemsNotADate(t *testing.T) {
+	names, date := parseItems("food for the cat", time.Now())
+	if !slices.Equal(names, []string{"food for the cat"}) || date != nil {
+		t.Errorf("unexpected result: %v %v", names, date)
+	}
+}
+
+func Test_GetKeyboardCapped(t *testing.T) {
+	items := make([]Item, maxChecklistItems+10)
+	for i := range items {
+		items[i] = Item{ID: int64(i + 1), Name: "milk"}
+	}
+	keyboard := getKeyboard(-100, items)
+	// One row per item shown, then the clear button
+	if len(keyboard.InlineKeyboard) != maxChecklistItems+1 {
+		t.Errorf("expected %d rows, got %d", maxChecklistItems+1, len(keyboard.InlineKeyboard))
+	}
+}
@@ -1,18 +1,23 @@
 package middleware
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"net/http"
 	"os"
 	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/alvinhuhhh/go-alfred/internal/config"
+	"github.com/alvinhuhhh/go-alfred/internal/util"
 	"github.com/go-telegram/bot"
 	"github.com/go-telegram/bot/models"
+	"github.com/gorilla/mux"
 	initdata "github.com/telegram-mini-apps/init-data-golang"
 )
 
@@ -47,9 +52,9 @@ func LogBotRequests(next bot.HandlerFunc) bot.HandlerFunc {
 
 func Auth(next http.Handler) http.Handler {
 	whitelist := []string{
-		"/api/webhook",
+		"/api/webhook", // verified by the bot, see bot.WithWebhookSecretToken
 		"/api/ping",
-		"/api/cron",
+		"/api/cron", // signed, see VerifyCronSignature
 	}
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -65,10 +70,16 @@ func Auth(next http.Handler) http.Handler {
 			return 
 		}
 
+		// Calendar feeds are polled with a per-chat token, see RequireChatMemberOrFeedToken
+		if r.URL.Query().Get("token") != "" && isFeedRoute(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		// Get raw init data
 		auth := r.Header.Get("Authorization")
 		authSplit := strings.Split(auth, " ")
-		if authSplit[0] != "tma" {
+		if len(authSplit) != 2 || authSplit[0] != "tma" {
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
@@ -87,6 +98,210 @@ func Auth(next http.Handler) http.Handler {
 			w.WriteHeader(http.StatusUnauthorized)
 			return
 		}
-		next.ServeHTTP(w, r)
+
+		// Make the caller available to handlers
+		data, err := initdata.Parse(authSplit[1])
+		if err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), initDataKey{}, data)))
 	})
 }
+
+// feedRoutes are the path templates of routes that can be polled with a feed token.
+var feedRoutes = []string{
+	"/api/dinners/{chatId}/export",
+}
+
+func isFeedRoute(r *http.Request) bool {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return false
+	}
+	tpl, err := route.GetPathTemplate()
+	return err == nil && slices.Contains(feedRoutes, tpl)
+}
+
+type initDataKey struct{}
+
+// InitDataFromContext returns the Mini App init data of a request authenticated by Auth.
+func InitDataFromContext(ctx context.Context) (initdata.InitData, bool) {
+	data, ok := ctx.Value(initDataKey{}).(initdata.InitData)
+	return data, ok
+}
+
+// ChatMembership checks whether a Telegram user belongs to a chat.
+type ChatMembership interface {
+	IsChatMember(ctx context.Context, chatId, userId int64) (bool, error)
+}
+
+// AuthorizeChat reports whether the caller of a request authenticated by Auth belongs to chatId.
+// Like Auth, it allows every request when not in Production.
+func AuthorizeChat(r *http.Request, m ChatMembership, chatId int64) (bool, error) {
+	if os.Getenv("GO_ENV") != "production" {
+		return true, nil
+	}
+	data, ok := InitDataFromContext(r.Context())
+	if !ok || data.User.ID == 0 {
+		return false, nil
+	}
+	return m.IsChatMember(r.Context(), chatId, data.User.ID)
+}
+
+// CheckChatMember is AuthorizeChat for handlers that only learn the chat from the request body or the
+// item they load. It writes an error response and returns false if the caller does not belong to chatId.
+func CheckChatMember(w http.ResponseWriter, r *http.Request, m ChatMembership, chatId int64) bool {
+	ok, err := AuthorizeChat(r, m, chatId)
+	if err != nil {
+		slog.Error(err.Error())
+		slog.Error("unable to check chat membership")
+		w.WriteHeader(http.StatusInternalServerError)
+		return false
+	}
+	if !ok {
+		slog.Warn(fmt.Sprintf("caller is not a member of chat id %d", chatId))
+		w.WriteHeader(http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// RequireChatMember rejects requests from callers who do not belong to the chat the request is for.
+func RequireChatMember(m ChatMembership, chatId func(r *http.Request) (int64, error)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, err := chatId(r)
+			if err != nil {
+				slog.Error("unable to parse chatId from request")
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if !CheckChatMember(w, r, m, id) {
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// FeedTokens checks the per-chat tokens that calendar feeds are polled with.
+type FeedTokens interface {
+	VerifyFeedToken(ctx context.Context, chatId int64, token string) (bool, error)
+}
+
+// RequireChatMemberOrFeedToken is RequireChatMember for routes that calendar apps poll with a
+// ?token= feed token instead of Mini App init data.
+func RequireChatMemberOrFeedToken(m ChatMembership, f FeedTokens, chatId func(r *http.Request) (int64, error)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		member := RequireChatMember(m, chatId)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			token := r.URL.Query().Get("token")
+			if token == "" {
+				member.ServeHTTP(w, r)
+				return
+			}
+			id, err := chatId(r)
+			if err != nil {
+				slog.Error("unable to parse chatId from request")
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			ok, err := f.VerifyFeedToken(r.Context(), id, token)
+			if err != nil {
+				slog.Error(err.Error())
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if !ok {
+				slog.Warn(fmt.Sprintf("invalid feed token for chat id %d", id))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ChatIdFromPath reads the chat id from a path variable.
+func ChatIdFromPath(name string) func(r *http.Request) (int64, error) {
+	return func(r *http.Request) (int64, error) {
+		return strconv.ParseInt(mux.Vars(r)[name], 10, 64)
+	}
+}
+
+// ChatIdFromQuery reads the chat id from a query parameter.
+func ChatIdFromQuery(name string) func(r *http.Request) (int64, error) {
+	return func(r *http.Request) (int64, error) {
+		return strconv.ParseInt(r.URL.Query().Get(name), 10, 64)
+	}
+}
+
+// NonceStore records nonces of signed requests so that each one can only be used once.
+type NonceStore interface {
+	UseNonce(ctx context.Context, nonce string, expiry time.Duration) (bool, error)
+}
+
+// VerifyCronSignature rejects requests that are not signed with the cron secret. Requests carry a
+// unix timestamp, a random nonce and an HMAC-SHA256 signature of "timestamp.nonce.body" in the
+// X-Alfred-Timestamp, X-Alfred-Nonce and X-Alfred-Signature headers.
+func VerifyCronSignature(nonces NonceStore) func(http.Handler) http.Handler {
+	// Maximum age of a signed request
+	window := 5 * time.Minute
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			secret, err := config.GetCronSecret()
+			if err != nil {
+				slog.Error(err.Error())
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			timestamp := r.Header.Get("X-Alfred-Timestamp")
+			nonce := r.Header.Get("X-Alfred-Nonce")
+			signature := r.Header.Get("X-Alfred-Signature")
+			if timestamp == "" || nonce == "" || signature == "" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			ts, err := strconv.ParseInt(timestamp, 10, 64)
+			if err != nil {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if age := time.Since(time.Unix(ts, 0)); age > window || age < -window {
+				slog.Warn("cron request timestamp outside of window")
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			if !util.VerifyRequest(secret, timestamp, nonce, body, signature) {
+				slog.Warn("invalid cron request signature")
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			// Only check the nonce once the request is known to be genuine
+			fresh, err := nonces.UseNonce(r.Context(), nonce, 2*window)
+			if err != nil {
+				slog.Error(err.Error())
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			if !fresh {
+				slog.Warn("replayed cron request")
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
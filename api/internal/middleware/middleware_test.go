@@ -0,0 +1,152 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alvinhuhhh/go-alfred/internal/util"
+	initdata "github.com/telegram-mini-apps/init-data-golang"
+)
+
+type memoryNonceStore map[string]bool
+
+func (m memoryNonceStore) UseNonce(ctx context.Context, nonce string, expiry time.Duration) (bool, error) {
+	if m[nonce] {
+		return false, nil
+	}
+	m[nonce] = true
+	return true, nil
+}
+
+func newCronRequest(body, timestamp, nonce, signature string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/api/cron", strings.NewReader(body))
+	r.Header.Set("X-Alfred-Timestamp", timestamp)
+	r.Header.Set("X-Alfred-Nonce", nonce)
+	r.Header.Set("X-Alfred-Signature", signature)
+	return r
+}
+
+func serveCron(nonces NonceStore, r *http.Request) int {
+	handler := VerifyCronSignature(nonces)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	return w.Code
+}
+
+func Test_VerifyCronSignature(t *testing.T) {
+	t.Setenv("CRON_SECRET", "secret")
+	nonces := memoryNonceStore{}
+	body := `{"chat_id": 1}`
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	signature := util.SignRequest("secret", ts, "abc", []byte(body))
+
+	if code := serveCron(nonces, newCronRequest(body, ts, "abc", signature)); code != http.StatusOK {
+		t.Errorf("expected signed request to be accepted, got %d", code)
+	}
+	if code := serveCron(nonces, newCronRequest(body, ts, "abc", signature)); code != http.StatusUnauthorized {
+		t.Errorf("expected replayed request to be rejected, got %d", code)
+	}
+}
+
+func Test_VerifyCronSignatureRejectsUnsigned(t *testing.T) {
+	t.Setenv("CRON_SECRET", "secret")
+	r := httptest.NewRequest(http.MethodPost, "/api/cron", strings.NewReader(`{"chat_id": 1}`))
+
+	if code := serveCron(memoryNonceStore{}, r); code != http.StatusUnauthorized {
+		t.Errorf("expected unsigned request to be rejected, got %d", code)
+	}
+}
+
+func Test_VerifyCronSignatureRejectsTamperedBody(t *testing.T) {
+	t.Setenv("CRON_SECRET", "secret")
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	signature := util.SignRequest("secret", ts, "abc", []byte(`{"chat_id": 1}`))
+
+	if code := serveCron(memoryNonceStore{}, newCronRequest(`{"chat_id": 2}`, ts, "abc", signature)); code != http.StatusUnauthorized {
+		t.Errorf("expected tampered request to be rejected, got %d", code)
+	}
+}
+
+func Test_VerifyCronSignatureRejectsStaleTimestamp(t *testing.T) {
+	t.Setenv("CRON_SECRET", "secret")
+	body := `{"chat_id": 1}`
+	ts := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+	signature := util.SignRequest("secret", ts, "abc", []byte(body))
+
+	if code := serveCron(memoryNonceStore{}, newCronRequest(body, ts, "abc", signature)); code != http.StatusUnauthorized {
+		t.Errorf("expected stale request to be rejected, got %d", code)
+	}
+}
+
+type fakeMembership map[int64][]int64
+
+func (f fakeMembership) IsChatMember(ctx context.Context, chatId, userId int64) (bool, error) {
+	return slices.Contains(f[chatId], userId), nil
+}
+
+func Test_RequireChatMember(t *testing.T) {
+	t.Setenv("GO_ENV", "production")
+	membership := fakeMembership{-100: {1}}
+	handler := RequireChatMember(membership, ChatIdFromQuery("chatId"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	cases := []struct {
+		url      string
+		userId   int64
+		expected int
+	}{
+		{"/api/encryption/key?chatId=-100", 1, http.StatusOK},
+		{"/api/encryption/key?chatId=-100", 2, http.StatusForbidden},
+		{"/api/encryption/key?chatId=-200", 1, http.StatusForbidden},
+		{"/api/encryption/key?chatId=abc", 1, http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		r := httptest.NewRequest(http.MethodGet, c.url, nil)
+		data := initdata.InitData{User: initdata.User{ID: c.userId}}
+		r = r.WithContext(context.WithValue(r.Context(), initDataKey{}, data))
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		if w.Code != c.expected {
+			t.Errorf("expected %d for user %d on %s, got %d", c.expected, c.userId, c.url, w.Code)
+		}
+	}
+}
+
+type fakeFeedTokens map[int64]string
+
+func (f fakeFeedTokens) VerifyFeedToken(ctx context.Context, chatId int64, token string) (bool, error) {
+	return f[chatId] != "" && f[chatId] == token, nil
+}
+
+func Test_RequireChatMemberOrFeedToken(t *testing.T) {
+	t.Setenv("GO_ENV", "production")
+	handler := RequireChatMemberOrFeedToken(fakeMembership{}, fakeFeedTokens{-100: "secret"}, ChatIdFromQuery("chatId"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	cases := []struct {
+		url      string
+		expected int
+	}{
+		{"/api/export?chatId=-100&token=secret", http.StatusOK},
+		{"/api/export?chatId=-100&token=wrong", http.StatusUnauthorized},
+		{"/api/export?chatId=-200&token=secret", http.StatusUnauthorized},
+		{"/api/export?chatId=-100", http.StatusForbidden},
+	}
+	for _, c := range cases {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, c.url, nil))
+		if w.Code != c.expected {
+			t.Errorf("expected %d on %s, got %d", c.expected, c.url, w.Code)
+		}
+	}
+}
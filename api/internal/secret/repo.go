@@ -7,6 +7,7 @@ import (
 )
 
 type Repo interface {
+	GetSecretById(ctx context.Context, id int64) (*Secret, error)
 	GetSecretsForChatId(ctx context.Context, id int64, limit, offet int) (*[]Secret, error)
 	InsertSecret(ctx context.Context, secret *Secret) error
 	DeleteSecret(ctx context.Context, id int64) error
@@ -20,6 +21,16 @@ func NewRepo(db *sqlx.DB) (Repo, error) {
 	return &repo{db: db}, nil
 }
 
+func (r repo) GetSecretById(ctx context.Context, id int64) (*Secret, error) {
+	query := "SELECT id, key, value, chat_id, key_version, iv_b64 FROM secrets WHERE id = ?"
+	query = r.db.Rebind(query)
+	var s Secret
+	if err := r.db.GetContext(ctx, &s, query, id); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
 func (r repo) GetSecretsForChatId(ctx context.Context, id int64, limit, offset int) (*[]Secret, error) {
 	query := "SELECT id, key, value, chat_id, key_version, iv_b64 FROM secrets WHERE chat_id = ? ORDER BY id DESC LIMIT ? OFFSET ?"
 	query = r.db.Rebind(query)
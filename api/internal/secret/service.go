@@ -1,13 +1,18 @@
 package secret
 
 import (
+	"database/sql"
 	"encoding/base64"
 	"encoding/json"
 	"log/slog"
 	"net/http"
+	"os"
 	"strconv"
 
+	"github.com/alvinhuhhh/go-alfred/internal/chat"
+	"github.com/alvinhuhhh/go-alfred/internal/middleware"
 	"github.com/alvinhuhhh/go-alfred/internal/util"
+	"github.com/go-telegram/bot"
 	"github.com/gorilla/mux"
 )
 
@@ -19,11 +24,14 @@ type Service interface {
 }
 
 type service struct {
-	repo Repo
+	bot         *bot.Bot
+	repo        Repo
+	membership  middleware.ChatMembership
+	permissions chat.Permissions
 }
 
-func NewService(r Repo) (Service, error) {
-	return &service{repo: r}, nil
+func NewService(b *bot.Bot, r Repo, m middleware.ChatMembership, p chat.Permissions) (Service, error) {
+	return &service{bot: b, repo: r, membership: m, permissions: p}, nil
 }
 
 func (s *service) GetDataEncryptionKey(w http.ResponseWriter, r *http.Request) {
@@ -93,6 +101,9 @@ func (s service) InsertSecret(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	if !middleware.CheckChatMember(w, r, s.membership, secret.ChatId) {
+		return
+	}
 	if err := s.repo.InsertSecret(r.Context(), &secret); err != nil {
 		slog.Error(err.Error())
 		slog.Error("unable to insert secret")
@@ -112,6 +123,20 @@ func (s service) DeleteSecret(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusBadRequest)
 		return
 	}
+	secret, err := s.repo.GetSecretById(r.Context(), id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		slog.Error(err.Error())
+		slog.Error("error fetching secret")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	if !middleware.CheckChatMember(w, r, s.membership, secret.ChatId) || !s.authorizeCommand(w, r, secret.ChatId, chat.CommandDeleteSecret) {
+		return
+	}
 	if err := s.repo.DeleteSecret(r.Context(), id); err != nil {
 		slog.Error(err.Error())
 		slog.Error("error deleting secret")
@@ -120,3 +145,24 @@ func (s service) DeleteSecret(w http.ResponseWriter, r *http.Request) {
 	}
 	w.WriteHeader(http.StatusOK)
 }
+
+// authorizeCommand checks the chat's permission policy for command, and writes an error response if the
+// caller may not run it. Like Auth, it allows every request when not in Production.
+func (s service) authorizeCommand(w http.ResponseWriter, r *http.Request, chatId int64, command string) bool {
+	if os.Getenv("GO_ENV") != "production" {
+		return true
+	}
+	data, _ := middleware.InitDataFromContext(r.Context())
+	ok, err := s.permissions.CanRun(r.Context(), s.bot, chatId, data.User.ID, command)
+	if err != nil {
+		slog.Error(err.Error())
+		slog.Error("unable to check permissions")
+		w.WriteHeader(http.StatusInternalServerError)
+		return false
+	}
+	if !ok {
+		w.WriteHeader(http.StatusForbidden)
+		return false
+	}
+	return true
+}
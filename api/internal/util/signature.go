@@ -0,0 +1,21 @@
+package util
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// SignRequest returns the hex encoded HMAC-SHA256 of "timestamp.nonce.body" keyed with secret.
+func SignRequest(secret, timestamp, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + nonce + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyRequest reports whether signature matches the request, in constant time.
+func VerifyRequest(secret, timestamp, nonce string, body []byte, signature string) bool {
+	expected := SignRequest(secret, timestamp, nonce, body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
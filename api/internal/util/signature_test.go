@@ -0,0 +1,32 @@
+package util
+
+import (
+	"testing"
+)
+
+func Test_SignRequest(t *testing.T) {
+	// echo -n '1760000000.abc.{"chat_id": 1}' | openssl dgst -sha256 -hmac secret
+	expected := "4ab6d7921df458123817eda08fff423b0407803f74211e1946a70100de93dad8"
+	actual := SignRequest("secret", "1760000000", "abc", []byte(`{"chat_id": 1}`))
+	if actual != expected {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+}
+
+func Test_VerifyRequest(t *testing.T) {
+	body := []byte(`{"chat_id": 1}`)
+	signature := SignRequest("secret", "1760000000", "abc", body)
+
+	if !VerifyRequest("secret", "1760000000", "abc", body, signature) {
+		t.Errorf("expected signature to verify")
+	}
+	if VerifyRequest("secret", "1760000000", "abc", []byte(`{"chat_id": 2}`), signature) {
+		t.Errorf("expected signature not to verify for a different body")
+	}
+	if VerifyRequest("secret", "1760000001", "abc", body, signature) {
+		t.Errorf("expected signature not to verify for a different timestamp")
+	}
+	if VerifyRequest("other", "1760000000", "abc", body, signature) {
+		t.Errorf("expected signature not to verify with a different secret")
+	}
+}
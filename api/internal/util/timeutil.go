@@ -0,0 +1,60 @@
+package util
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DateIn returns midnight of the calendar date that t falls on in loc.
+func DateIn(t time.Time, loc *time.Location) time.Time {
+	year, month, day := t.In(loc).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, loc)
+}
+
+// TimeOnDate returns the given wall clock time (HH:MM) on the calendar date of date, in loc.
+func TimeOnDate(date time.Time, clock string, loc *time.Location) (time.Time, error) {
+	c, err := time.Parse("15:04", clock)
+	if err != nil {
+		return time.Time{}, err
+	}
+	year, month, day := date.Date()
+	return time.Date(year, month, day, c.Hour(), c.Minute(), 0, 0, loc), nil
+}
+
+// ParseDate parses a user supplied date relative to today. It accepts "today", "tonight",
+// "tomorrow", weekday names such as "sat" or "saturday" (the next such day, including today),
+// ISO dates such as "2026-10-24", and day/month dates such as "24/10" or "24/10/2026".
+func ParseDate(s string, today time.Time) (time.Time, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	switch s {
+	case "", "today", "tonight":
+		return today, nil
+	case "tomorrow", "tmr":
+		return today.AddDate(0, 0, 1), nil
+	}
+
+	for i := range 7 {
+		day := today.AddDate(0, 0, i)
+		name := strings.ToLower(day.Weekday().String())
+		if s == name || s == name[:3] {
+			return day, nil
+		}
+	}
+
+	for _, layout := range []string{"2006-01-02", "02/01/2006", "2/1/2006"} {
+		if t, err := time.ParseInLocation(layout, s, today.Location()); err == nil {
+			return t, nil
+		}
+	}
+	for _, layout := range []string{"02/01", "2/1"} {
+		if t, err := time.ParseInLocation(layout, s, today.Location()); err == nil {
+			d := time.Date(today.Year(), t.Month(), t.Day(), 0, 0, 0, 0, today.Location())
+			if d.Before(today) {
+				d = d.AddDate(1, 0, 0)
+			}
+			return d, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unable to parse date: %s", s)
+}
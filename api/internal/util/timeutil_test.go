@@ -0,0 +1,100 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func Test_DateInBeforeMidnight(t *testing.T) {
+	sg := mustLoadLocation(t, "Asia/Singapore")
+	now := time.Date(2026, 10, 18, 15, 59, 0, 0, time.UTC) // 23:59 in Singapore
+
+	actual := DateIn(now, sg).Format("2006-01-02")
+	if actual != "2026-10-18" {
+		t.Errorf("expected 2026-10-18, got %s", actual)
+	}
+}
+
+func Test_DateInAfterMidnight(t *testing.T) {
+	sg := mustLoadLocation(t, "Asia/Singapore")
+	now := time.Date(2026, 10, 18, 16, 0, 0, 0, time.UTC) // 00:00 the next day in Singapore
+
+	actual := DateIn(now, sg).Format("2006-01-02")
+	if actual != "2026-10-19" {
+		t.Errorf("expected 2026-10-19, got %s", actual)
+	}
+}
+
+func Test_DateInBehindUTC(t *testing.T) {
+	ny := mustLoadLocation(t, "America/New_York")
+	now := time.Date(2026, 10, 19, 3, 30, 0, 0, time.UTC) // 23:30 the previous day in New York
+
+	actual := DateIn(now, ny).Format("2006-01-02")
+	if actual != "2026-10-18" {
+		t.Errorf("expected 2026-10-18, got %s", actual)
+	}
+}
+
+func Test_TimeOnDate(t *testing.T) {
+	sg := mustLoadLocation(t, "Asia/Singapore")
+	date := time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC) // dates are read from the database as UTC
+
+	actual, err := TimeOnDate(date, "17:30", sg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := time.Date(2026, 10, 18, 9, 30, 0, 0, time.UTC)
+	if !actual.Equal(expected) {
+		t.Errorf("expected %s, got %s", expected, actual)
+	}
+}
+
+func Test_TimeOnDateInvalid(t *testing.T) {
+	if _, err := TimeOnDate(time.Now(), "25:00", time.UTC); err == nil {
+		t.Errorf("expected error for invalid time")
+	}
+}
+
+func Test_ParseDate(t *testing.T) {
+	today := time.Date(2026, 10, 21, 0, 0, 0, 0, time.UTC) // Wednesday
+
+	cases := map[string]string{
+		"":           "2026-10-21",
+		"today":      "2026-10-21",
+		"tomorrow":   "2026-10-22",
+		"Wed":        "2026-10-21",
+		"sat":        "2026-10-24",
+		"saturday":   "2026-10-24",
+		"tue":        "2026-10-27",
+		"2026-10-24": "2026-10-24",
+		"24/10/2026": "2026-10-24",
+		"24/10":      "2026-10-24",
+		"1/1":        "2027-01-01",
+	}
+	for input, expected := range cases {
+		actual, err := ParseDate(input, today)
+		if err != nil {
+			t.Errorf("unexpected error for %q: %s", input, err)
+			continue
+		}
+		if actual.Format("2006-01-02") != expected {
+			t.Errorf("expected %s for %q, got %s", expected, input, actual.Format("2006-01-02"))
+		}
+	}
+}
+
+func Test_ParseDateInvalid(t *testing.T) {
+	today := time.Date(2026, 10, 21, 0, 0, 0, 0, time.UTC)
+
+	if _, err := ParseDate("someday", today); err == nil {
+		t.Errorf("expected error for invalid date")
+	}
+}
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS "public"."chat_members" (
+    "chat_id" bigint NOT NULL REFERENCES "public"."chats"("id") ON DELETE CASCADE,
+    "user_id" bigint NOT NULL,
+    "first_name" "text" NOT NULL DEFAULT '',
+    "last_name" "text" NOT NULL DEFAULT '',
+    "username" "text" NOT NULL DEFAULT '',
+    "role" "text" NOT NULL DEFAULT 'member' CHECK ("role" IN ('admin', 'member', 'left')),
+    "updated_at" timestamp with time zone NOT NULL DEFAULT now(),
+    PRIMARY KEY ("chat_id", "user_id")
+);
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS "public"."chat_permissions" (
+    "chat_id" bigint NOT NULL REFERENCES "public"."chats"("id") ON DELETE CASCADE,
+    "command" "text" NOT NULL,
+    "role" "text" NOT NULL CHECK ("role" IN ('admin', 'member')),
+    PRIMARY KEY ("chat_id", "command")
+);
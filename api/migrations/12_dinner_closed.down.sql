@@ -0,0 +1 @@
+ALTER TABLE "public"."dinners" DROP COLUMN IF EXISTS "closed_at";
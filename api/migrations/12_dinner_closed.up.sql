@@ -0,0 +1 @@
+ALTER TABLE "public"."dinners" ADD COLUMN IF NOT EXISTS "closed_at" timestamp with time zone;
@@ -0,0 +1 @@
+ALTER TABLE "public"."chats" DROP COLUMN IF EXISTS "feed_token";
@@ -0,0 +1 @@
+ALTER TABLE "public"."chats" ADD COLUMN IF NOT EXISTS "feed_token" "text" NOT NULL DEFAULT '';
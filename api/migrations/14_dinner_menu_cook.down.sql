@@ -0,0 +1,3 @@
+ALTER TABLE "public"."dinners" DROP COLUMN IF EXISTS "cook_name";
+ALTER TABLE "public"."dinners" DROP COLUMN IF EXISTS "cook_id";
+ALTER TABLE "public"."dinners" DROP COLUMN IF EXISTS "menu";
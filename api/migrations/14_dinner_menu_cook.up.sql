@@ -0,0 +1,3 @@
+ALTER TABLE "public"."dinners" ADD COLUMN IF NOT EXISTS "menu" "text" NOT NULL DEFAULT '';
+ALTER TABLE "public"."dinners" ADD COLUMN IF NOT EXISTS "cook_id" bigint;
+ALTER TABLE "public"."dinners" ADD COLUMN IF NOT EXISTS "cook_name" "text" NOT NULL DEFAULT '';
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS "public"."groceries" (
+    "id" bigint NOT NULL PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY (
+        SEQUENCE NAME "public"."groceries_id_seq"
+        START WITH 1
+        INCREMENT BY 1
+        NO MINVALUE
+        NO MAXVALUE
+        CACHE 1
+    ),
+    "chat_id" bigint NOT NULL REFERENCES "public"."chats"("id") ON DELETE CASCADE,
+    "name" "text" NOT NULL,
+    "dinner_date" "date",
+    "added_by" bigint NOT NULL DEFAULT 0,
+    "added_by_name" "text" NOT NULL DEFAULT '',
+    "done" boolean NOT NULL DEFAULT false,
+    "created_at" timestamp with time zone NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS "groceries_chat_id_idx" ON "public"."groceries" ("chat_id");
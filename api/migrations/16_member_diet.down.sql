@@ -0,0 +1 @@
+ALTER TABLE "public"."chat_members" DROP COLUMN IF EXISTS "diet";
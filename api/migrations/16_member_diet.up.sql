@@ -0,0 +1 @@
+ALTER TABLE "public"."chat_members" ADD COLUMN IF NOT EXISTS "diet" "text"[] NOT NULL DEFAULT '{}';
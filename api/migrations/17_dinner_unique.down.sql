@@ -0,0 +1 @@
+DROP INDEX IF EXISTS "public"."dinners_chat_id_date_idx";
@@ -0,0 +1,57 @@
+-- Dinners created twice for the same chat and date are merged into the
+-- oldest one before the unique index is added.
+
+-- The latest response of each user wins
+INSERT INTO "public"."dinner_attendees" ("dinner_id", "user_id", "name", "status", "guests", "eta", "updated_at")
+SELECT DISTINCT ON (dd."keep_id", a."user_id") dd."keep_id", a."user_id", a."name", a."status", a."guests", a."eta", a."updated_at"
+FROM "public"."dinner_attendees" a
+JOIN (
+    SELECT * FROM (
+        SELECT "id", min("id") OVER (PARTITION BY "chat_id", "date") AS "keep_id"
+        FROM "public"."dinners"
+    ) AS all_dinners WHERE "id" <> "keep_id"
+) dd ON dd."id" = a."dinner_id"
+ORDER BY dd."keep_id", a."user_id", a."updated_at" DESC
+ON CONFLICT ("dinner_id", "user_id") DO UPDATE SET
+    "name" = EXCLUDED."name",
+    "status" = EXCLUDED."status",
+    "guests" = EXCLUDED."guests",
+    "eta" = EXCLUDED."eta",
+    "updated_at" = EXCLUDED."updated_at"
+WHERE EXCLUDED."updated_at" > "public"."dinner_attendees"."updated_at";
+
+-- Every poll message stays live, and details missing on the kept dinner are taken from its duplicates
+UPDATE "public"."dinners" d SET
+    "message_ids" = d."message_ids" || m."message_ids",
+    "locked_at" = coalesce(d."locked_at", m."locked_at"),
+    "closed_at" = coalesce(d."closed_at", m."closed_at"),
+    "menu" = CASE WHEN d."menu" = '' THEN m."menu" ELSE d."menu" END,
+    "cook_id" = CASE WHEN d."cook_name" = '' THEN m."cook_id" ELSE d."cook_id" END,
+    "cook_name" = CASE WHEN d."cook_name" = '' THEN m."cook_name" ELSE d."cook_name" END
+FROM (
+    SELECT dd."keep_id",
+        array_agg(mid ORDER BY x."id", mid_ord) FILTER (WHERE mid IS NOT NULL) AS "message_ids",
+        min(x."locked_at") AS "locked_at",
+        min(x."closed_at") AS "closed_at",
+        coalesce((array_agg(x."menu" ORDER BY x."id") FILTER (WHERE x."menu" <> ''))[1], '') AS "menu",
+        (array_agg(x."cook_id" ORDER BY x."id") FILTER (WHERE x."cook_name" <> ''))[1] AS "cook_id",
+        coalesce((array_agg(x."cook_name" ORDER BY x."id") FILTER (WHERE x."cook_name" <> ''))[1], '') AS "cook_name"
+    FROM (
+        SELECT * FROM (
+            SELECT "id", min("id") OVER (PARTITION BY "chat_id", "date") AS "keep_id"
+            FROM "public"."dinners"
+        ) AS all_dinners WHERE "id" <> "keep_id"
+    ) dd
+    JOIN "public"."dinners" x ON x."id" = dd."id"
+    LEFT JOIN LATERAL unnest(x."message_ids") WITH ORDINALITY AS u(mid, mid_ord) ON true
+    GROUP BY dd."keep_id"
+) m
+WHERE d."id" = m."keep_id";
+
+DELETE FROM "public"."dinners" d
+WHERE EXISTS (
+    SELECT 1 FROM "public"."dinners" k
+    WHERE k."chat_id" = d."chat_id" AND k."date" = d."date" AND k."id" < d."id"
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS "dinners_chat_id_date_idx" ON "public"."dinners" ("chat_id", "date");
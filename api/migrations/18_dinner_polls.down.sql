@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS "public"."dinner_polls";
+ALTER TABLE "public"."chats" DROP COLUMN IF EXISTS "dinner_mode";
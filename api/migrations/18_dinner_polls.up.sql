@@ -0,0 +1,12 @@
+ALTER TABLE "public"."chats" ADD COLUMN IF NOT EXISTS "dinner_mode" "text" NOT NULL DEFAULT 'buttons'
+    CHECK ("dinner_mode" IN ('buttons', 'poll'));
+
+-- Native Telegram polls posted for a dinner, so that poll answers can be
+-- matched back to it. Polls are removed once they have been stopped.
+CREATE TABLE IF NOT EXISTS "public"."dinner_polls" (
+    "id" "text" NOT NULL PRIMARY KEY,
+    "dinner_id" bigint NOT NULL REFERENCES "public"."dinners"("id") ON DELETE CASCADE,
+    "message_id" bigint NOT NULL,
+    "created_at" timestamp with time zone NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS "dinner_polls_dinner_id_idx" ON "public"."dinner_polls" ("dinner_id");
@@ -0,0 +1,3 @@
+-- Set when a member can't be messaged privately because they haven't started
+-- a chat with the bot, and cleared once they do.
+ALTER TABLE "public"."chat_members" ADD COLUMN IF NOT EXISTS "dm_blocked_at" timestamp with time zone;
@@ -0,0 +1,5 @@
+ALTER TABLE "public"."dinners" DROP COLUMN IF EXISTS "nudged_at";
+
+ALTER TABLE "public"."chats" DROP COLUMN IF EXISTS "quiet_hours_end";
+ALTER TABLE "public"."chats" DROP COLUMN IF EXISTS "quiet_hours_start";
+ALTER TABLE "public"."chats" DROP COLUMN IF EXISTS "nudge_interval";
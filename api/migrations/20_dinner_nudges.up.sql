@@ -0,0 +1,7 @@
+-- Nudges mention members who haven't responded to dinner every
+-- nudge_interval minutes until the cutoff. 0 turns them off.
+ALTER TABLE "public"."chats" ADD COLUMN IF NOT EXISTS "nudge_interval" integer NOT NULL DEFAULT 0;
+ALTER TABLE "public"."chats" ADD COLUMN IF NOT EXISTS "quiet_hours_start" "text" NOT NULL DEFAULT '';
+ALTER TABLE "public"."chats" ADD COLUMN IF NOT EXISTS "quiet_hours_end" "text" NOT NULL DEFAULT '';
+
+ALTER TABLE "public"."dinners" ADD COLUMN IF NOT EXISTS "nudged_at" timestamp with time zone;
@@ -0,0 +1 @@
+-- pgcrypto is left installed, as it may have been there before this migration
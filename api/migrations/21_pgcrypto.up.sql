@@ -0,0 +1,2 @@
+-- hmac() in the pg_cron commands that call /api/cron, see cron.Repo.Schedule
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
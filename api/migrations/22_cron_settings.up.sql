@@ -0,0 +1,7 @@
+-- Settings read by pg_cron jobs when they run, such as the secret that signs requests to /api/cron.
+-- The app stores the current values on startup, see cron.PgCronScheduler.Start.
+CREATE TABLE IF NOT EXISTS "public"."cron_settings" (
+    "name" "text" NOT NULL PRIMARY KEY,
+    "value" "text" NOT NULL,
+    "updated_at" timestamp with time zone NOT NULL DEFAULT now()
+);
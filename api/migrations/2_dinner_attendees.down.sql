@@ -0,0 +1,11 @@
+ALTER TABLE "public"."dinners" ADD COLUMN IF NOT EXISTS "yes" "text"[] NOT NULL DEFAULT '{}';
+ALTER TABLE "public"."dinners" ADD COLUMN IF NOT EXISTS "no" "text"[] NOT NULL DEFAULT '{}';
+
+UPDATE "public"."dinners" d SET
+    "yes" = COALESCE((SELECT array_agg(a."name" ORDER BY a."updated_at") FROM "public"."dinner_attendees" a WHERE a."dinner_id" = d."id" AND a."status" = 'YES'), '{}'),
+    "no" = COALESCE((SELECT array_agg(a."name" ORDER BY a."updated_at") FROM "public"."dinner_attendees" a WHERE a."dinner_id" = d."id" AND a."status" = 'NO'), '{}');
+
+ALTER TABLE "public"."dinners" ALTER COLUMN "yes" DROP DEFAULT;
+ALTER TABLE "public"."dinners" ALTER COLUMN "no" DROP DEFAULT;
+
+DROP TABLE IF EXISTS "public"."dinner_attendees";
@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS "public"."dinner_attendees" (
+    "dinner_id" bigint NOT NULL REFERENCES "public"."dinners"("id") ON DELETE CASCADE,
+    "user_id" bigint NOT NULL,
+    "name" "text" NOT NULL,
+    "status" "text" NOT NULL,
+    "updated_at" timestamp with time zone NOT NULL DEFAULT now(),
+    PRIMARY KEY ("dinner_id", "user_id")
+);
+
+-- Existing RSVPs only recorded first names, so they are backfilled with
+-- negative placeholder user ids. A placeholder is replaced by the real user
+-- the next time someone with the same name responds to that dinner.
+INSERT INTO "public"."dinner_attendees" ("dinner_id", "user_id", "name", "status")
+SELECT "dinner_id", -row_number() OVER (PARTITION BY "dinner_id" ORDER BY "status" DESC, "ord"), "name", "status"
+FROM (
+    SELECT d."id" AS "dinner_id", y."name", 'YES' AS "status", y."ord"
+    FROM "public"."dinners" d, unnest(d."yes") WITH ORDINALITY AS y("name", "ord")
+    UNION ALL
+    SELECT d."id" AS "dinner_id", n."name", 'NO' AS "status", n."ord"
+    FROM "public"."dinners" d, unnest(d."no") WITH ORDINALITY AS n("name", "ord")
+) AS legacy
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE "public"."dinners" DROP COLUMN IF EXISTS "yes";
+ALTER TABLE "public"."dinners" DROP COLUMN IF EXISTS "no";
@@ -0,0 +1 @@
+-- Trimmed message ids cannot be restored
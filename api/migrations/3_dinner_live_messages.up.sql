@@ -0,0 +1,4 @@
+-- Poll messages are now edited in place, so only the most recent message of
+-- each dinner is still expected to be live.
+UPDATE "public"."dinners" SET "message_ids" = "message_ids"[array_upper("message_ids", 1):array_upper("message_ids", 1)]
+WHERE array_length("message_ids", 1) > 1;
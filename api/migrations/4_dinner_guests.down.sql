@@ -0,0 +1 @@
+ALTER TABLE "public"."dinner_attendees" DROP COLUMN IF EXISTS "guests";
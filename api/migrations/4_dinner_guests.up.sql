@@ -0,0 +1 @@
+ALTER TABLE "public"."dinner_attendees" ADD COLUMN IF NOT EXISTS "guests" integer NOT NULL DEFAULT 0;
@@ -0,0 +1,4 @@
+UPDATE "public"."dinner_attendees" SET "status" = 'YES' WHERE "status" IN ('LATE', 'TAKEAWAY');
+DELETE FROM "public"."dinner_attendees" WHERE "status" = 'MAYBE';
+ALTER TABLE "public"."dinner_attendees" DROP CONSTRAINT IF EXISTS "dinner_attendees_status_check";
+ALTER TABLE "public"."dinner_attendees" DROP COLUMN IF EXISTS "eta";
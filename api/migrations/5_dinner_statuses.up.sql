@@ -0,0 +1,3 @@
+ALTER TABLE "public"."dinner_attendees" ADD COLUMN IF NOT EXISTS "eta" "text" NOT NULL DEFAULT '';
+ALTER TABLE "public"."dinner_attendees" ADD CONSTRAINT "dinner_attendees_status_check"
+    CHECK ("status" IN ('YES', 'NO', 'MAYBE', 'LATE', 'TAKEAWAY'));
@@ -0,0 +1,4 @@
+ALTER TABLE "public"."dinners" DROP COLUMN IF EXISTS "reopened";
+ALTER TABLE "public"."dinners" DROP COLUMN IF EXISTS "locked_at";
+
+ALTER TABLE "public"."chats" DROP COLUMN IF EXISTS "dinner_cutoff";
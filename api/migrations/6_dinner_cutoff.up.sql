@@ -0,0 +1,4 @@
+ALTER TABLE "public"."chats" ADD COLUMN IF NOT EXISTS "dinner_cutoff" "text" NOT NULL DEFAULT '';
+
+ALTER TABLE "public"."dinners" ADD COLUMN IF NOT EXISTS "locked_at" timestamp with time zone;
+ALTER TABLE "public"."dinners" ADD COLUMN IF NOT EXISTS "reopened" boolean NOT NULL DEFAULT false;
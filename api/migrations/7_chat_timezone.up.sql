@@ -0,0 +1 @@
+ALTER TABLE "public"."chats" ADD COLUMN IF NOT EXISTS "timezone" "text" NOT NULL DEFAULT '';
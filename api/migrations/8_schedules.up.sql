@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS "public"."schedules" (
+    "id" bigint NOT NULL PRIMARY KEY GENERATED BY DEFAULT AS IDENTITY (
+        SEQUENCE NAME "public"."schedules_id_seq"
+        START WITH 1
+        INCREMENT BY 1
+        NO MINVALUE
+        NO MAXVALUE
+        CACHE 1
+    ),
+    "chat_id" bigint NOT NULL REFERENCES "public"."chats"("id") ON DELETE CASCADE,
+    "job" "text" NOT NULL,
+    "days" "text" NOT NULL,
+    "time" "text" NOT NULL,
+    "cron_expression" "text" NOT NULL,
+    "created_at" timestamp with time zone NOT NULL DEFAULT now()
+);
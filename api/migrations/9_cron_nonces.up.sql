@@ -0,0 +1,4 @@
+CREATE TABLE IF NOT EXISTS "public"."cron_nonces" (
+    "nonce" "text" NOT NULL PRIMARY KEY,
+    "created_at" timestamp with time zone NOT NULL DEFAULT now()
+);